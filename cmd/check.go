@@ -0,0 +1,166 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// package cmd ...
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/normalize"
+	"github.com/ax-i-om/tempest/internal/providers"
+	"github.com/ax-i-om/tempest/internal/resolve"
+	"github.com/ax-i-om/tempest/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check <url>",
+	Short: "Validate a single link and print its metadata",
+	Long: `
+Validate a single cloud storage/file sharing link using the module
+that handles its host, and print the extracted metadata as a table
+(default) or as JSON (--json).
+
+Defanged links (e.g. hxxps://mega[.]nz/...) are refanged, and links 
+to known URL shorteners are resolved before the module is picked.
+
+Exit status:
+  0  the link is valid/online
+  1  the link is dead or invalid, or its content was removed
+  2  no module handles the link, the module cannot parse it
+     (e.g. a homepage rather than a file/folder link), or an
+     error occurred`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		globals.DebugFlag, err = cmd.Flags().GetBool("debug")
+		if err != nil {
+			fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
+			globals.DebugFlag = false
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		// Undo any defanging/obfuscation of the link
+		link, _ := normalize.Line(strings.TrimSpace(args[0]))
+
+		// Resolve shortened links to their final destination
+		var chain []string
+		if shortened, _ := resolve.Extract(link); len(shortened) > 0 {
+			chain, err = resolve.Resolve(link)
+			if err != nil {
+				handlers.LogErr(err, "failed to resolve shortened link "+link)
+			}
+			link = chain[len(chain)-1]
+		}
+
+		p := providers.Find(link)
+		if p == nil {
+			fmt.Fprintln(os.Stderr, "No module handles", link)
+			os.Exit(2)
+		}
+		// The host is handled, but the link must also have a format the module recognizes (e.g. a Mega file/folder
+		// link rather than its homepage), otherwise the module would find nothing to validate
+		if found, err := p.Extract(link); err != nil || len(found) == 0 {
+			fmt.Fprintln(os.Stderr, p.Name+":", link, "is not a link the module can parse")
+			os.Exit(2)
+		}
+
+		// Delegate the link to the module, which validates it and extracts its metadata
+		results, err := p.Delegate(link, "")
+		if err != nil {
+			handlers.LogErr(err, "failed to check "+link)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(2)
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(os.Stderr, p.Name+":", link, "is dead or invalid")
+			os.Exit(1)
+		}
+		removed := true
+		for _, v := range results {
+			removed = removed && v.Status == models.Removed
+		}
+		for i := range results {
+			if len(chain) > 1 {
+				results[i].Redirects = chain
+			}
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			for _, v := range results {
+				if err := enc.Encode(v); err != nil {
+					fmt.Fprintf(os.Stderr, "%s\n", err)
+					os.Exit(2)
+				}
+			}
+		} else {
+			for _, v := range results {
+				printEntry(v)
+			}
+		}
+		if removed {
+			fmt.Fprintln(os.Stderr, p.Name+":", link, "was removed (e.g. following a DMCA notice)")
+			os.Exit(1)
+		}
+	},
+}
+
+// printEntry prints the non-empty fields of an entry, followed by its children (if any), as a table
+func printEntry(v models.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := handlers.CSVRow(v)
+	for i, h := range handlers.CSVHeaders {
+		if h == "children" || row[i] == "" || row[i] == "0" {
+			continue
+		}
+		// Counts that are unavailable are stored as negative sentinels (-1) and are left out as well
+		if n, err := strconv.Atoi(row[i]); err == nil && n < 0 && (h == "filecount" || h == "downloads" || h == "views") {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", h, row[i])
+	}
+	w.Flush()
+
+	if len(v.Children) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tSIZE\tLINK")
+		for _, c := range v.Children {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.Type, c.Size, c.Link)
+		}
+		w.Flush()
+	}
+	fmt.Println()
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().Bool("json", false, "print the result as JSON")
+}
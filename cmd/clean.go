@@ -36,10 +36,10 @@ var cleanCmd = &cobra.Command{
 The clean function is used to validate/deduplicate JSON/CSV files
 that are generated by Tempest.
 
-If you decide to output the results to a JSON file specifically, 
-it will not be valid JSON. The clean function will automatically
-convert the JSON invalid file formatting into valid json; however,
-reusing this file for results will cause further formatting issues. 
+JSON files are read regardless of the layout they were written in
+(ndjson, array, or the {...}, lines written by older versions) and 
+the deduplicated entries are written as a JSON array (default) or 
+as ndjson (--format ndjson).
 
 Note: Unlike other functions in Tempest, a file 
 extension (.json/.csv) will not be automatically appended. When 
@@ -54,6 +54,12 @@ cleaning, you must specify the file extension.`,
 				fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
 				globals.DebugFlag = false
 			}
+			// Validate the json output format before anything is read or written
+			format, _ := cmd.Flags().GetString("format")
+			if format != handlers.NDJSON && format != handlers.JSONArray {
+				fmt.Fprintln(os.Stderr, "Unknown format:", format, "(expected ndjson or array)")
+				return
+			}
 			// Set output mode to clean
 			fmt.Println("Output Mode:", globals.Mode)
 			if strings.Contains(args[0], ".json") {
@@ -61,34 +67,27 @@ cleaning, you must specify the file extension.`,
 				globals.Filename = handlers.FixName(args[0], ".json")
 				fmt.Println("File Name:", globals.Filename)
 				fmt.Println()
-				// Remove any duplicate lines from json file
-				err := handlers.Deduplicate(globals.Filename)
+				// Read the entries of the json file, whichever layout it was written in
+				entries, err := handlers.ReadEntries(globals.Filename)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "%s\n", err)
-					handlers.LogErr(err, "failed to deduplicate "+globals.Filename)
+					handlers.LogErr(err, "failed to read "+globals.Filename)
 					return
 				}
 
-				// Attempt to read the specified json file
-				content, err := os.ReadFile("clean-" + globals.Filename)
+				// Remove any duplicate entries
+				entries = handlers.DeduplicateEntries(entries)
+
+				// Encode the entries in the specified format
+				comp, err := handlers.EncodeJSON(entries, format)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "%s\n", err)
-					handlers.LogErr(err, "failed to read clean-"+globals.Filename)
+					handlers.LogErr(err, "failed to encode cleaned json")
 					return
 				}
 
-				// Trim newline from end of file
-				middle := strings.TrimRight(string(content), "\n")
-				// Trim the rightmost comma from end file
-				middle = strings.TrimRight(middle, ",")
-				// Append two tabs to the beginning of each entry (formatting)
-				middle = strings.ReplaceAll(middle, "{\"source\":\"", "\t\t{\"source\":\"")
-
-				// Combine the strings
-				comp := "{\n\t\"content\":[\n" + middle + "\n\t]\n}"
-
-				// Attempt to write the combined strings new a new file, with a name based on the specified filename
-				err = os.WriteFile("clean-"+globals.Filename, []byte(comp), 0600)
+				// Attempt to write the entries to a new file, with a name based on the specified filename
+				err = os.WriteFile("clean-"+globals.Filename, comp, 0600)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "%s\n", err)
 					handlers.LogErr(err, "failed to write cleaned json to file")
@@ -128,4 +127,5 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// cleanCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	cleanCmd.Flags().String("format", "array", "format of the cleaned json file: array or ndjson")
 }
@@ -20,7 +20,6 @@ package cmd
 
 import (
 	"encoding/csv"
-	"errors"
 	"fmt"
 	"os"
 
@@ -42,7 +41,11 @@ the terminal **ONCE** and wait until the remaining goroutines
 finish executing (typically <60s) In order to forcefully shut 
 down Tempest press "Ctrl + C" in the terminal **TWICE**
 CAUTION: FORCEFULLY SHUTTING DOWN TEMPEST MAY RESULT IN ISSUES 
-INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION`,
+INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION
+
+Files written by older versions of Tempest (with fewer or 
+differently ordered columns) are migrated once, keeping the 
+original as <file>.bak`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
 			cmd.Usage()
@@ -54,7 +57,6 @@ INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION`,
 				globals.DebugFlag = false
 			}
 			launch := true
-			var existed bool
 			// Set output mode to csv
 			globals.Mode = "csv"
 			// Set filename to args[2], append .csv if necessary
@@ -62,51 +64,24 @@ INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION`,
 			fmt.Println("Output Mode:", globals.Mode)
 			fmt.Println("File Name:", globals.Filename)
 			fmt.Println()
-			// Set the globally declared jsonfile variable to filename
-			globals.Csvfile, err = os.OpenFile(globals.Filename, os.O_WRONLY|os.O_APPEND, 0600)
-			// Set existed to true, if it didn't exist, this will be set to false
-			existed = true
-			if err != nil {
-				// Check if the error occurred because the file doesn't exist
-				if errors.Is(err, os.ErrNotExist) {
-					handlers.LogErr(err, "failed to open csv file: "+globals.Filename+" because it doesn't exist")
-					// If file doesn't exist, create one
-					globals.Csvfile, err = os.OpenFile(globals.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
-					if err != nil { // Error when attempting to create CSV file, meaning issues could occur when trying to call write()
-						handlers.LogErr(err, "failed to create new csv file: "+globals.Filename+" after discovering it didn't exist")
-						fmt.Fprintf(os.Stderr, "%s\n", err)
-						// Close all files/flush all writers
-						handlers.Wipe()
-						launch = false
-					}
-					// If error doesn't occur when trying to create file, this means one likely did not already exist or may have been overwritten; therefore,
-					// set existed flag to false
-					existed = false
-				} else { // An error unrelated to a files existence/lack-thereof occurred, resulting in an inability to create/open csvfile
-					handlers.LogErr(err, "failed to open csv file: "+globals.Filename+" due to an unexpected error")
-					// Close all files/flush all writers
-					handlers.Wipe()
-					fmt.Fprintf(os.Stderr, "%s\n", err)
-					launch = false
-				}
+			// Set the globally declared csvfile variable to filename, create one with a header row if it doesn't exist
+			// (migrating it if its header differs)
+			var migrated bool
+			globals.Csvfile, migrated, err = handlers.OpenCSV(globals.Filename)
+			if migrated {
+				fmt.Println("Migrated", globals.Filename, "to the current columns, the original was kept as", globals.Filename+".bak")
+				fmt.Println()
 			}
-			// Create a new *csv.Writer that writes to csvfile, assign to globally declared variable writer
-			globals.Writer = csv.NewWriter(globals.Csvfile)
-			handlers.LogInfo("CSV Writer initialized")
-			if !existed { // Check if the specified csv file already existed by referencing the existed flag, if it did not exist:
-				// Create/format headers string slice
-				headers := []string{"source", "link", "title", "description", "service", "uploaded", "mtime", "type", "size", "filecount", "thumbnail", "downloads", "views", "hash", "malware"}
-				// Write headers
-				err := globals.Writer.Write(headers)
-				if err != nil { //
-					handlers.LogErr(err, "failed to write headers to new csv file")
-					// Close all files/flush all writers
-					handlers.Wipe()
-					fmt.Fprintf(os.Stderr, "%s\n", err)
-					launch = false
-				}
-				// Flush writer to ensure contents were written
-				globals.Writer.Flush()
+			if err != nil { // Error when attempting to open/create CSV file, meaning issues could occur when trying to call write()
+				handlers.LogErr(err, "failed to open csv file for writing")
+				// Close all files/flush all writers
+				handlers.Wipe()
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				launch = false
+			} else {
+				// Create a new *csv.Writer that writes to csvfile, assign to globally declared variable writer
+				globals.Writer = csv.NewWriter(globals.Csvfile)
+				handlers.LogInfo("CSV Writer initialized")
 			}
 			if launch {
 				worker.Launch()
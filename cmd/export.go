@@ -0,0 +1,127 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// package cmd ...
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ax-i-om/tempest/internal/export"
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export JSON/CSV files generated by Tempest for threat intelligence platforms",
+	Long: `
+Export the results written to a JSON/CSV file by Tempest as a
+STIX 2.1 bundle (tempest export stix) or a MISP event (tempest 
+export misp).
+
+Pastes and links become URL observables/objects, with "references"
+relationships from each paste to the links found within it, and
+the entry metadata is kept as custom properties/attributes. Ids 
+are deterministic, so exporting the same results twice produces
+the same objects.
+
+Note: Unlike other functions in Tempest, a file extension 
+(.json/.csv) will not be automatically appended.`,
+}
+
+// stixCmd represents the export stix command
+var stixCmd = &cobra.Command{
+	Use:   "stix <filename|filepath>",
+	Short: "Export a JSON/CSV file generated by Tempest as a STIX 2.1 bundle",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport(cmd, args[0], func(entries []models.Entry) interface{} {
+			return export.STIX(entries)
+		})
+	},
+}
+
+// mispCmd represents the export misp command
+var mispCmd = &cobra.Command{
+	Use:   "misp <filename|filepath>",
+	Short: "Export a JSON/CSV file generated by Tempest as a MISP event",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport(cmd, args[0], func(entries []models.Entry) interface{} {
+			info, _ := cmd.Flags().GetString("info")
+			if info == "" {
+				info = "Tempest results: " + filepath.Base(args[0])
+			}
+			return export.MISPEventFrom(info, entries)
+		})
+	},
+}
+
+// runExport reads the entries of a result file, converts them and writes the JSON encoded result to the file
+// specified by --output (or stdout)
+func runExport(cmd *cobra.Command, filename string, convert func([]models.Entry) interface{}) {
+	var err error
+	globals.DebugFlag, err = cmd.Flags().GetBool("debug")
+	if err != nil {
+		fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
+		globals.DebugFlag = false
+	}
+	out, _ := cmd.Flags().GetString("output")
+
+	// Read the entries of the result file
+	entries, err := handlers.ReadEntries(filename)
+	if err != nil {
+		handlers.LogErr(err, "failed to read "+filename)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(convert(handlers.DeduplicateEntries(entries)), "", "  ")
+	if err != nil {
+		handlers.LogErr(err, "failed to encode export")
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	if out == "" {
+		fmt.Println(string(data))
+		return
+	}
+	err = os.WriteFile(out, append(data, '\n'), 0600)
+	if err != nil {
+		handlers.LogErr(err, "failed to write export to "+out)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "Exported", len(entries), "entries to", out)
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(stixCmd)
+	exportCmd.AddCommand(mispCmd)
+
+	exportCmd.PersistentFlags().StringP("output", "o", "", "file the export is written to (default: stdout)")
+	mispCmd.Flags().String("info", "", "info (title) of the MISP event")
+}
@@ -40,7 +40,15 @@ the terminal **ONCE** and wait until the remaining goroutines
 finish executing (typically <60s) In order to forcefully shut 
 down Tempest press "Ctrl + C" in the terminal **TWICE**
 CAUTION: FORCEFULLY SHUTTING DOWN TEMPEST MAY RESULT IN ISSUES 
-INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION`,
+INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION
+
+Formats (--format):
+  ndjson  one JSON entry per line, readable by line based tools 
+          while Tempest is running (default)
+  array   a single JSON array, kept valid after every write
+
+Files written by older versions of Tempest ({...}, lines) or in the
+other format are migrated once, keeping the original as <file>.bak`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
 			cmd.Usage()
@@ -54,13 +62,25 @@ INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION`,
 			launch := true
 			// Set output mode to json
 			globals.Mode = "json"
+			// Set the json output format
+			globals.JSONFormat, _ = cmd.Flags().GetString("format")
+			if globals.JSONFormat != handlers.NDJSON && globals.JSONFormat != handlers.JSONArray {
+				fmt.Fprintln(os.Stderr, "Unknown format:", globals.JSONFormat, "(expected ndjson or array)")
+				return
+			}
 			// Set filename to args[2], append .json if necessary
 			globals.Filename = handlers.FixName(args[0], ".json")
 			fmt.Println("Output Mode:", globals.Mode)
+			fmt.Println("Format:", globals.JSONFormat)
 			fmt.Println("File Name:", globals.Filename)
 			fmt.Println()
-			// Set the globally declared jsonfile variable to filename, create one if it doesn't exist
-			globals.Jsonfile, err = os.OpenFile(globals.Filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+			// Set the globally declared jsonfile variable to filename, create one if it doesn't exist (migrating it if necessary)
+			var migrated bool
+			globals.Jsonfile, migrated, err = handlers.OpenJSON(globals.Filename, globals.JSONFormat)
+			if migrated {
+				fmt.Println("Migrated", globals.Filename, "to the", globals.JSONFormat, "format, the original was kept as", globals.Filename+".bak")
+				fmt.Println()
+			}
 			if err != nil { // Error when attempting to open/create JSON file, meaning issues could occur when trying to call write()
 				handlers.LogErr(err, "failed to open json file for writing")
 				// Close all files/flush all writers
@@ -87,4 +107,5 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// jsonCmd.Flags().BoolP("debug", "d", false, "print debug information to the console")
+	jsonCmd.Flags().String("format", "ndjson", "json output format: ndjson or array")
 }
@@ -0,0 +1,215 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// package cmd ...
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/notices"
+	"github.com/ax-i-om/tempest/internal/providers"
+	"github.com/ax-i-om/tempest/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// noticesCmd represents the notices command
+var noticesCmd = &cobra.Command{
+	Use:   "notices <filename|filepath>",
+	Short: "Generate takedown notices per hosting service from a JSON/CSV file generated by Tempest",
+	Long: `
+Generate one takedown (e.g. DMCA/abuse) notice per hosting service
+from the results written to a JSON/CSV file by Tempest. Links are
+grouped by service and listed with their titles, sizes, and first
+seen dates.
+
+The config directory (--config) contains config.json, holding the
+sender details and the abuse contact of each service, and a
+templates directory holding Go text/templates named after the 
+service (e.g. templates/google-drive.tmpl), falling back to 
+templates/default.tmpl. Run "tempest notices init <dir>" to create
+an example config directory.
+
+Every link is validated again before the notices are written, and
+links that are no longer online are left out. Links that cannot
+be rechecked (for example because of a network error) are kept,
+and their number is printed as a warning. Append 
+--recheck=false to skip this; the notices then include every link
+of the file, whether it is still online or not.
+
+Links whose content is recorded as removed (status column) are
+always left out.
+
+Note: Unlike other functions in Tempest, a file extension 
+(.json/.csv) will not be automatically appended.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		globals.DebugFlag, err = cmd.Flags().GetBool("debug")
+		if err != nil {
+			fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
+			globals.DebugFlag = false
+		}
+		dir, _ := cmd.Flags().GetString("config")
+		out, _ := cmd.Flags().GetString("out")
+		recheck, _ := cmd.Flags().GetBool("recheck")
+
+		cfg, err := notices.LoadConfig(dir)
+		if err != nil {
+			handlers.LogErr(err, "failed to load notices config from "+dir)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+
+		// Read the entries of the result file
+		entries, err := handlers.ReadEntries(args[0])
+		if err != nil {
+			handlers.LogErr(err, "failed to read "+args[0])
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+
+		// Content that was already removed needs no notice
+		var pending []models.Entry
+		for _, v := range entries {
+			if v.Status != models.Removed {
+				pending = append(pending, v)
+			}
+		}
+		entries = pending
+
+		if recheck {
+			fmt.Println("Rechecking links, please wait...")
+			before := len(entries)
+			var unchecked int
+			entries, unchecked = live(entries)
+			fmt.Println("Left out", before-len(entries), "entries whose links are no longer online")
+			if unchecked > 0 {
+				fmt.Fprintln(os.Stderr, "Warning:", unchecked, "links could not be rechecked and were kept")
+			}
+		} else {
+			fmt.Println("Warning: links were not rechecked (--recheck=false), the notices may include links that are no longer online")
+		}
+
+		grouped := notices.Group(entries)
+		for service := range grouped {
+			if cfg.Contacts[service].To == "" {
+				fmt.Fprintln(os.Stderr, "Warning: no abuse contact configured for", service)
+			}
+		}
+
+		written, err := cfg.Write(out, grouped)
+		for _, w := range written {
+			fmt.Println("Notice written to", w)
+		}
+		if err != nil {
+			handlers.LogErr(err, "failed to write notices to "+out)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// noticesInitCmd represents the notices init command
+var noticesInitCmd = &cobra.Command{
+	Use:   "init <directory>",
+	Short: "Create an example notices config directory",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var services []string
+		for _, p := range providers.Providers {
+			services = append(services, p.Name)
+		}
+		err := notices.Init(args[0], services)
+		if err != nil {
+			handlers.LogErr(err, "failed to initialize notices config in "+args[0])
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Example config written to", args[0])
+	},
+}
+
+// live validates the links of entries again and returns the entries whose links are still online, alongside the
+// number of links that could not be rechecked. Entries whose link is not handled by any module, or whose recheck
+// failed, are kept.
+func live(entries []models.Entry) ([]models.Entry, int) {
+	status := make(map[string]bool)
+	var links []string
+	for _, v := range entries {
+		if _, ok := status[v.Link]; !ok {
+			links = append(links, v.Link)
+		}
+		status[v.Link] = true
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var unchecked int
+	sem := make(chan struct{}, 8)
+	for _, link := range links {
+		p := providers.Find(link)
+		if p == nil {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(link string, p *providers.Provider) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results, err := p.Delegate(link, "")
+			if err != nil {
+				handlers.LogErr(err, "failed to recheck "+link)
+				mu.Lock()
+				unchecked++
+				mu.Unlock()
+				return
+			}
+			online := false
+			for _, v := range results {
+				online = online || v.Status != models.Removed
+			}
+			if !online {
+				mu.Lock()
+				status[link] = false
+				mu.Unlock()
+			}
+		}(link, p)
+	}
+	wg.Wait()
+
+	var alive []models.Entry
+	for _, v := range entries {
+		if status[v.Link] {
+			alive = append(alive, v)
+		}
+	}
+	return alive, unchecked
+}
+
+func init() {
+	rootCmd.AddCommand(noticesCmd)
+	noticesCmd.AddCommand(noticesInitCmd)
+
+	noticesCmd.Flags().String("config", "notices", "config directory containing config.json and templates")
+	noticesCmd.Flags().String("out", "notices-out", "directory the notices are written to")
+	noticesCmd.Flags().Bool("recheck", true, "validate links again and leave out those that are no longer online (--recheck=false includes unchecked links)")
+}
@@ -0,0 +1,98 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// package cmd ...
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report <filename|filepath>",
+	Short: "Generate a self-contained HTML report from a JSON/CSV file generated by Tempest",
+	Long: `
+Generate a single, self-contained HTML page from the results
+written to a JSON/CSV file by Tempest. Entries are grouped by
+service and source paste, thumbnails are shown inline, and the
+page offers sorting/filtering by size, type, views and date.
+
+Thumbnails are downloaded into the page, so that it can be viewed
+fully offline and opening it performs no requests. Thumbnails that
+cannot be embedded, or all thumbnails if --embed-thumbnails=false
+is appended, are shown as links instead of images.
+
+Note: Unlike other functions in Tempest, a file extension 
+(.json/.csv) will not be automatically appended.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		globals.DebugFlag, err = cmd.Flags().GetBool("debug")
+		if err != nil {
+			fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
+			globals.DebugFlag = false
+		}
+		out, _ := cmd.Flags().GetString("html")
+		embed, _ := cmd.Flags().GetBool("embed-thumbnails")
+
+		// Read the entries of the result file
+		entries, err := handlers.ReadEntries(args[0])
+		if err != nil {
+			handlers.LogErr(err, "failed to read "+args[0])
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+
+		rep := report.Build(filepath.Base(args[0]), handlers.DeduplicateEntries(entries))
+		if embed {
+			fmt.Println("Embedding thumbnails, please wait...")
+			report.EmbedThumbnails(&rep)
+		}
+
+		file, err := os.Create(out)
+		if err != nil {
+			handlers.LogErr(err, "failed to create "+out)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		err = report.Render(file, rep)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			handlers.LogErr(err, "failed to render report to "+out)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Report of", rep.Total, "entries written to", out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().String("html", "report.html", "file the HTML report is written to")
+	reportCmd.Flags().Bool("embed-thumbnails", true, "download thumbnails into the report (--embed-thumbnails=false links them instead)")
+}
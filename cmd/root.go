@@ -21,7 +21,11 @@ package cmd
 import (
 	"os"
 
+	"github.com/ax-i-om/tempest/internal/globals"
 	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/internal/stream"
+	"github.com/ax-i-om/tempest/internal/webhook"
 	"github.com/spf13/cobra"
 )
 
@@ -69,4 +73,16 @@ func init() {
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "print debug information to the console")
+	rootCmd.PersistentFlags().StringVar(&webhook.Default.URL, "webhook", "", "POST results as JSON to the specified webhook URL")
+	rootCmd.PersistentFlags().StringVar(&webhook.Default.Preset, "webhook-preset", webhook.Default.Preset, "webhook payload shape: json, slack or discord")
+	rootCmd.PersistentFlags().IntVar(&webhook.Default.BatchSize, "webhook-batch", webhook.Default.BatchSize, "number of results sent per webhook request (1 sends results one by one)")
+	rootCmd.PersistentFlags().DurationVar(&webhook.Default.Interval, "webhook-interval", webhook.Default.Interval, "maximum time a result waits before its partial batch is sent")
+	rootCmd.PersistentFlags().StringArrayVar(&webhook.Default.Headers, "webhook-header", nil, "additional webhook request header formatted as \"Key: Value\" (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&webhook.Default.Secret, "webhook-secret", "", "secret used to sign webhook request bodies with HMAC-SHA256 ("+webhook.SignatureHeader+" header)")
+	rootCmd.PersistentFlags().IntVar(&webhook.Default.MaxRetries, "webhook-retries", webhook.Default.MaxRetries, "number of retries of a failed webhook request before it is spooled")
+	rootCmd.PersistentFlags().StringVar(&webhook.Default.SpoolDir, "webhook-spool", "", "directory undelivered webhook payloads are spooled to and retried from")
+	rootCmd.PersistentFlags().StringVar(&stream.Addr, "stream", "", "serve live results as Server-Sent Events (/api/stream) and over WebSocket (/api/ws) on the specified address, e.g. :8081")
+	rootCmd.PersistentFlags().StringArrayVar(&stream.AllowedOrigins, "stream-origin", nil, "allow web pages served from the specified origin (e.g. https://dashboard.example.com, or * for any) to connect to /api/ws (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&metrics.Addr, "metrics", "", "serve Prometheus metrics (/metrics) on the specified address, e.g. :9090")
+	rootCmd.PersistentFlags().StringVar(&globals.HarvestFile, "harvest", "", "record links to hosts without a module and write a report of them to the specified JSON file on shutdown")
 }
@@ -0,0 +1,145 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// package cmd ...
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/server"
+	"github.com/ax-i-om/tempest/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Launch Tempest as an HTTP API server",
+	Long: `
+Launch Tempest as an HTTP API server, used to start/stop scans and
+query their results. Results are kept in memory, and are passed to
+any enabled sinks (e.g. --webhook).
+
+Endpoints:
+  GET    /api/status     state and counters of the current scan
+  POST   /api/scan       start a scan {"sources":[],"providers":[]}
+  DELETE /api/scan       stop the current scan
+  GET    /api/entries    page through results, filtered by service,
+                         type, source and q (offset, limit)
+  POST   /api/extract    validate a link or extract the links in 
+                         text {"url":"","text":"","providers":[]}
+  GET    /api/providers  list the registered modules
+  GET    /api/sources    list the paste sites that can be scanned
+  GET    /api/stream     stream results as Server-Sent Events
+  GET    /api/ws         stream results over a WebSocket
+
+In order to shut down Tempest, press "Ctrl + C" in the terminal.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		globals.DebugFlag, err = cmd.Flags().GetBool("debug")
+		if err != nil {
+			fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
+			globals.DebugFlag = false
+		}
+		addr, _ := cmd.Flags().GetString("addr")
+		scan, _ := cmd.Flags().GetBool("scan")
+
+		// Set mode to serve, results are only passed to the sinks
+		globals.Mode = "serve"
+		fmt.Println("Output Mode: Serve")
+		fmt.Println("Listening on:", addr)
+		fmt.Println()
+
+		// Register the sinks enabled by the command line flags
+		if err := worker.StartSinks(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			handlers.Wipe()
+			os.Exit(1)
+		}
+
+		srv := server.New()
+		httpServer := &http.Server{Addr: addr, Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+		// Disconnect streaming clients on shutdown, so their connections do not hold up the shutdown
+		httpServer.RegisterOnShutdown(func() { _ = srv.Hub.Close() })
+
+		if scan {
+			scanner, err := worker.NewScanner(nil, nil)
+			if err == nil {
+				err = srv.Start(scanner)
+			}
+			if err != nil {
+				handlers.LogErr(err, "failed to start scan")
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				handlers.Wipe()
+				os.Exit(1)
+			}
+		}
+
+		// Shut down gracefully on interrupt. done is closed once in-flight requests (e.g. /api/extract validating
+		// links) have finished, so the sinks are not closed while a request can still write to them.
+		done := make(chan struct{})
+		sigChannel := make(chan os.Signal, 1)
+		signal.Notify(sigChannel, os.Interrupt)
+		go func() {
+			defer close(done)
+			<-sigChannel
+			fmt.Println("  ->  Attempting to gracefully shutdown Tempest")
+			fmt.Println("\nWaiting for", globals.Wg.GetCount(), "GoRoutines to finish execution. Please wait... (~15s)")
+			go func() {
+				<-sigChannel // forceful
+				os.Exit(2)
+			}()
+			// Requests may be waiting on the 15s HTTP client timeout of the modules
+			cntx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+			if err := httpServer.Shutdown(cntx); err != nil {
+				handlers.LogErr(err, "api server did not shut down gracefully")
+			}
+		}()
+
+		err = httpServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			handlers.LogErr(err, "api server failed")
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			handlers.Wipe()
+			os.Exit(1)
+		}
+
+		// Wait for in-flight requests, then stop the running scan, close all files/flush all writers, write the
+		// harvest report
+		<-done
+		srv.Stop()
+		worker.Shutdown()
+		fmt.Println("Tempest was gracefully shut down")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("addr", ":8080", "address the API server listens on")
+	serveCmd.Flags().Bool("scan", false, "start scanning all sources with all providers immediately")
+}
@@ -0,0 +1,85 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// package cmd ...
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/sqlite"
+	"github.com/ax-i-om/tempest/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+// sqliteCmd represents the sqlite command
+var sqliteCmd = &cobra.Command{
+	Use:   "sqlite <filename|filepath>",
+	Short: "Launch Tempest and output results to the specified SQLite database",
+	Long: `
+Launch Tempest and output results to the specified SQLite database
+
+Entries are upserted by link: repeat links update the stored
+metadata, last seen timestamp and sighting count, while every paste 
+a link was seen in is recorded in the sightings table.
+
+In order to gracefully shut down Tempest, press "Ctrl + C" in 
+the terminal **ONCE** and wait until the remaining goroutines 
+finish executing (typically <60s) In order to forcefully shut 
+down Tempest press "Ctrl + C" in the terminal **TWICE**
+CAUTION: FORCEFULLY SHUTTING DOWN TEMPEST MAY RESULT IN ISSUES 
+INCLUDING, BUT NOT LIMITED TO, DATA LOSS AND FILE CORRUPTION`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			cmd.Usage()
+		} else {
+			var err error
+			globals.DebugFlag, err = cmd.Flags().GetBool("debug")
+			if err != nil {
+				fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
+				globals.DebugFlag = false
+			}
+			launch := true
+			// Set output mode to sqlite
+			globals.Mode = "sqlite"
+			// Set filename to args[2], append .db if necessary
+			globals.Filename = handlers.FixName(args[0], ".db")
+			fmt.Println("Output Mode:", globals.Mode)
+			fmt.Println("File Name:", globals.Filename)
+			fmt.Println()
+			// Set the globally declared db variable to the opened database, create one if it doesn't exist
+			globals.DB, err = sqlite.Open(globals.Filename)
+			if err != nil { // Error when attempting to open/create the database, meaning issues could occur when trying to call write()
+				handlers.LogErr(err, "failed to open sqlite database for writing")
+				// Close all files/flush all writers
+				handlers.Wipe()
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				launch = false
+			}
+			if launch {
+				worker.Launch()
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sqliteCmd)
+}
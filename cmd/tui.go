@@ -0,0 +1,151 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// package cmd ...
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/tui"
+	"github.com/ax-i-om/tempest/internal/worker"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+// tuiCmd represents the tui command
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Launch Tempest with an interactive terminal dashboard",
+	Long: `
+Launch Tempest with a full-screen terminal dashboard showing live
+throughput, the in-flight goroutine count, per-provider hit 
+tallies, an error breakdown and a table of recent entries.
+
+Results are only kept on screen (and passed to any enabled sinks, 
+e.g. --webhook), unless --output is specified, in which case they
+are also written to the specified JSON (NDJSON) file. Debug 
+information (--debug) is written to tempest-debug.log.
+
+Keys:
+  p          pause/resume the generation of paste URLs
+  up/down    select a provider (also k/j)
+  space      enable/disable the selected provider (also enter/t)
+  a          enable every provider
+  n          enable only the selected provider
+  PgUp/PgDn  scroll through the recent entries
+  q          gracefully shut down Tempest (also Ctrl + C)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		globals.DebugFlag, err = cmd.Flags().GetBool("debug")
+		if err != nil {
+			fmt.Println("Something went wrong when trying to set Debug mode, continuing without debug")
+			globals.DebugFlag = false
+		}
+		output, _ := cmd.Flags().GetString("output")
+
+		// Debug information would be drawn over the dashboard, write it to a file instead
+		if globals.DebugFlag {
+			logfile, err := os.OpenFile("tempest-debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				return
+			}
+			defer logfile.Close()
+			globals.Logger = globals.Logger.Output(zerolog.ConsoleWriter{Out: logfile, NoColor: true})
+		}
+
+		// Set mode to tui, results are only passed to the sinks (and written to the output file, if specified)
+		globals.Mode = "tui"
+		if output != "" {
+			globals.Mode = "json"
+			globals.Filename = handlers.FixName(output, ".json")
+			var migrated bool
+			globals.Jsonfile, migrated, err = handlers.OpenJSON(globals.Filename, globals.JSONFormat)
+			if migrated {
+				fmt.Println("Migrated", globals.Filename, "to the", globals.JSONFormat, "format, the original was kept as", globals.Filename+".bak")
+			}
+			if err != nil {
+				handlers.LogErr(err, "failed to open json file for writing")
+				handlers.Wipe()
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				return
+			}
+		}
+
+		// Register the sinks enabled by the command line flags, and the dashboard
+		if err := worker.StartSinks(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			handlers.Wipe()
+			os.Exit(1)
+		}
+		scanner, err := worker.NewScanner(nil, nil)
+		if err != nil {
+			handlers.LogErr(err, "failed to create scanner")
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			handlers.Wipe()
+			os.Exit(1)
+		}
+		dashboard := tui.New(scanner)
+		handlers.Sinks = append(handlers.Sinks, dashboard)
+
+		if err := scanner.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			handlers.Wipe()
+			os.Exit(1)
+		}
+		// Show the dashboard until the user quits
+		err = dashboard.Run()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+
+		// Force the shutdown on interrupt
+		sigChannel := make(chan os.Signal, 1)
+		signal.Notify(sigChannel, os.Interrupt)
+		go func() {
+			<-sigChannel
+			os.Exit(2)
+		}()
+
+		fmt.Println("Attempting to gracefully shutdown Tempest")
+		fmt.Println("\nWaiting for", globals.Wg.GetCount(), "GoRoutines to finish execution. Please wait... (~15s)")
+		_ = scanner.Stop()
+		// Close all files/flush all writers, write the harvest report
+		worker.Shutdown()
+		fmt.Println("Tempest was gracefully shut down")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// tuiCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// tuiCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	tuiCmd.Flags().StringP("output", "o", "", "also write results to the specified JSON (NDJSON) file")
+}
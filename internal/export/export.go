@@ -0,0 +1,100 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package export contains functions used to convert entries into formats consumed by threat intelligence platforms,
+// namely STIX 2.1 bundles and MISP events.
+package export
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"time"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Namespace is the UUIDv5 namespace (b5e1c6a4-1d6f-4f0e-9a57-7a8d3c1f2e90) used for deterministic ids of objects
+// created by Tempest (other than STIX cyber observables, which use the namespace defined by the STIX specification)
+var Namespace = [16]byte{0xb5, 0xe1, 0xc6, 0xa4, 0x1d, 0x6f, 0x4f, 0x0e, 0x9a, 0x57, 0x7a, 0x8d, 0x3c, 0x1f, 0x2e, 0x90}
+
+// UUID5 returns the name based (SHA-1, version 5) UUID of name within namespace, as defined by RFC 4122
+func UUID5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	s := hex.EncodeToString(u)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// timestamp returns the time an entry was seen, or now if unknown, formatted as a STIX/MISP timestamp
+func timestamp(v models.Entry, now time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339, v.Seen); err == nil {
+		return t.UTC()
+	}
+	return now.UTC()
+}
+
+// metadata returns the non-empty metadata fields of an entry, keyed by their JSON names
+func metadata(v models.Entry) map[string]interface{} {
+	m := make(map[string]interface{})
+	add := func(k string, val interface{}) {
+		switch x := val.(type) {
+		case string:
+			if x == "" {
+				return
+			}
+		case int:
+			if x == 0 {
+				return
+			}
+		case []string:
+			if len(x) == 0 {
+				return
+			}
+		}
+		m[k] = val
+	}
+	add("title", v.Title)
+	add("description", v.Description)
+	add("service", v.Service)
+	add("uploaded", v.Uploaded)
+	add("mtime", v.Mtime)
+	add("duration", v.Duration)
+	add("type", v.Type)
+	add("mimetype", v.MimeType)
+	add("size", v.Size)
+	add("filecount", v.FileCount)
+	add("owner", v.Owner)
+	add("resolution", v.Resolution)
+	add("direct", v.Direct)
+	add("thumbnail", v.Thumbnail)
+	add("downloads", v.Downloads)
+	add("views", v.Views)
+	add("members", v.Members)
+	add("hash", v.Hash)
+	add("althash", v.AltHash)
+	add("malware", v.Malware)
+	add("trackers", v.Trackers)
+	add("obfuscation", v.Obfuscation)
+	add("redirects", v.Redirects)
+	add("status", v.Status)
+	add("seen", v.Seen)
+	return m
+}
@@ -0,0 +1,111 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package export
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestUUID5(t *testing.T) {
+	dns := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	tests := []struct {
+		namespace [16]byte
+		name      string
+		want      string
+	}{
+		{dns, "python.org", "886313e1-3b8a-5372-9b90-0c9aee199e5d"},
+		{stixNamespace, `{"value":"https://example.com/x"}`, "fa27c0d6-0d7b-535c-acfe-5ec8978d746d"},
+		{Namespace, "identity|tempest", "879677cb-5237-519c-a5d0-8668fb007260"},
+	}
+	for _, tt := range tests {
+		if got := UUID5(tt.namespace, tt.name); got != tt.want {
+			t.Errorf("UUID5(%x, %q) = %q, want %q", tt.namespace, tt.name, got, tt.want)
+		}
+	}
+}
+
+// hostile contains entries with values that must be escaped/encoded by the exporters
+var hostile = []models.Entry{
+	{Source: "https://rentry.co/abcde/raw", Link: `https://mega.nz/file/a'b\c`, Title: `"><script>alert(1)</script>`, Service: "Mega", Seen: "2023-01-02T03:04:05Z", Obfuscation: `x"y`},
+	{Source: "https://rentry.co/abcde/raw", Link: `https://mega.nz/file/a'b\c`, Service: "Mega"},
+	{Link: "magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=\u202etxt.exe", Service: "BitTorrent", Seen: "not a date"},
+}
+
+func TestSTIX(t *testing.T) {
+	bundle := STIX(hostile)
+	if _, err := json.Marshal(bundle); err != nil {
+		t.Fatalf("bundle cannot be encoded: %v", err)
+	}
+	ids := make(map[string]bool)
+	var pattern string
+	for _, o := range bundle.Objects {
+		id := o["id"].(string)
+		if ids[id] {
+			t.Errorf("duplicate object %s", id)
+		}
+		ids[id] = true
+		if o["type"] == "indicator" && strings.Contains(o["pattern"].(string), "mega.nz") {
+			pattern = o["pattern"].(string)
+		}
+	}
+	if want := `[url:value = 'https://mega.nz/file/a\'b\\c']`; pattern != want {
+		t.Errorf("pattern = %s, want %s", pattern, want)
+	}
+	// 1 identity, 2 links with an indicator and a relationship each, 1 paste and 1 references relationship
+	if len(bundle.Objects) != 9 {
+		t.Errorf("got %d objects, want 9", len(bundle.Objects))
+	}
+	if again := STIX(hostile); again.ID != bundle.ID {
+		t.Errorf("bundle id is not deterministic: %s != %s", again.ID, bundle.ID)
+	}
+}
+
+func TestMISP(t *testing.T) {
+	event := MISPEventFrom("test", hostile).Event
+	if _, err := json.Marshal(event); err != nil {
+		t.Fatalf("event cannot be encoded: %v", err)
+	}
+	// 2 link objects and 1 paste object, the paste references the link once
+	if len(event.Object) != 3 {
+		t.Fatalf("got %d objects, want 3", len(event.Object))
+	}
+	var refs int
+	var tags []string
+	for _, o := range event.Object {
+		refs += len(o.ObjectReference)
+		for _, a := range o.Attribute {
+			for _, tag := range a.Tag {
+				tags = append(tags, tag.Name)
+			}
+		}
+	}
+	if refs != 1 {
+		t.Errorf("got %d references, want 1", refs)
+	}
+	if !strings.Contains(strings.Join(tags, "\n"), `tempest:obfuscation="x"y"`) {
+		t.Errorf("missing obfuscation tag in %q", tags)
+	}
+	reversed := []models.Entry{hostile[2], hostile[1], hostile[0]}
+	if again := MISPEventFrom("test", reversed).Event; again.UUID != event.UUID {
+		t.Errorf("event uuid depends on the order of entries: %s != %s", again.UUID, event.UUID)
+	}
+}
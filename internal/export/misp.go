@@ -0,0 +1,163 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// mispURLTemplate is the uuid of the MISP url object template
+const mispURLTemplate = "60efb77b-40b5-4c46-871b-ed1ed999fce5"
+
+// MISPAttribute represents a MISP attribute
+type MISPAttribute struct {
+	UUID           string    `json:"uuid"`
+	Type           string    `json:"type"`
+	Category       string    `json:"category"`
+	ObjectRelation string    `json:"object_relation,omitempty"`
+	Value          string    `json:"value"`
+	Comment        string    `json:"comment,omitempty"`
+	ToIDS          bool      `json:"to_ids"`
+	Timestamp      string    `json:"timestamp"`
+	Tag            []MISPTag `json:"Tag,omitempty"`
+}
+
+// MISPTag represents a MISP tag
+type MISPTag struct {
+	Name string `json:"name"`
+}
+
+// MISPReference represents a reference from one MISP object to another
+type MISPReference struct {
+	UUID             string `json:"uuid"`
+	ObjectUUID       string `json:"object_uuid"`
+	ReferencedUUID   string `json:"referenced_uuid"`
+	RelationshipType string `json:"relationship_type"`
+	Timestamp        string `json:"timestamp"`
+}
+
+// MISPObject represents a MISP object
+type MISPObject struct {
+	UUID            string          `json:"uuid"`
+	Name            string          `json:"name"`
+	MetaCategory    string          `json:"meta-category"`
+	TemplateUUID    string          `json:"template_uuid"`
+	TemplateVersion string          `json:"template_version"`
+	Description     string          `json:"description"`
+	Comment         string          `json:"comment,omitempty"`
+	Timestamp       string          `json:"timestamp"`
+	Attribute       []MISPAttribute `json:"Attribute"`
+	ObjectReference []MISPReference `json:"ObjectReference,omitempty"`
+}
+
+// MISPEvent represents the contents of a MISP event
+type MISPEvent struct {
+	UUID          string       `json:"uuid"`
+	Info          string       `json:"info"`
+	Date          string       `json:"date"`
+	Timestamp     string       `json:"timestamp"`
+	ThreatLevelID string       `json:"threat_level_id"`
+	Analysis      string       `json:"analysis"`
+	Distribution  string       `json:"distribution"`
+	Published     bool         `json:"published"`
+	Tag           []MISPTag    `json:"Tag"`
+	Object        []MISPObject `json:"Object"`
+}
+
+// MISP represents a MISP event JSON document, as accepted by the MISP event import
+type MISP struct {
+	Event MISPEvent `json:"Event"`
+}
+
+// MISPEventFrom converts entries into a MISP event. Source pastes and links become url objects; the entry metadata
+// is stored as a text attribute (JSON encoded) and tempest: tags, and each paste object references the link objects
+// found within it. Uuids are deterministic, so importing the same entries twice updates the same objects.
+func MISPEventFrom(info string, entries []models.Entry) MISP {
+	now := time.Now().UTC()
+	objects := make(map[string]*MISPObject)
+	var order []string
+	var links []string
+
+	urlObject := func(value, comment string, ts time.Time) *MISPObject {
+		id := UUID5(Namespace, "misp-object|"+value)
+		if o, ok := objects[id]; ok {
+			return o
+		}
+		stamp := fmt.Sprint(ts.Unix())
+		o := &MISPObject{
+			UUID: id, Name: "url", MetaCategory: "network", TemplateUUID: mispURLTemplate, TemplateVersion: "9",
+			Description: "url object describes an url along with its normalized field", Comment: comment, Timestamp: stamp,
+			Attribute: []MISPAttribute{{UUID: UUID5(Namespace, "misp-attribute|url|"+value), Type: "url", Category: "Network activity", ObjectRelation: "url", Value: value, Timestamp: stamp}},
+		}
+		objects[id] = o
+		order = append(order, id)
+		return o
+	}
+
+	for _, v := range entries {
+		ts := timestamp(v, now)
+		stamp := fmt.Sprint(ts.Unix())
+		link := urlObject(v.Link, v.Service+" link found by Tempest", ts)
+		if len(link.Attribute) == 1 {
+			links = append(links, v.Link)
+			var tags []MISPTag
+			for _, t := range []struct{ k, v string }{{"service", v.Service}, {"type", v.Type}, {"obfuscation", v.Obfuscation}, {"status", v.Status}} {
+				if t.v != "" {
+					tags = append(tags, MISPTag{Name: "tempest:" + t.k + "=\"" + t.v + "\""})
+				}
+			}
+			link.Attribute[0].Tag = tags
+			if meta, err := json.Marshal(metadata(v)); err == nil {
+				link.Attribute = append(link.Attribute, MISPAttribute{UUID: UUID5(Namespace, "misp-attribute|text|"+v.Link), Type: "text", Category: "Other", ObjectRelation: "text", Value: string(meta), Comment: "Tempest entry metadata", Timestamp: stamp})
+			}
+		}
+
+		if v.Source == "" {
+			continue
+		}
+		paste := urlObject(v.Source, "Paste", ts)
+		ref := UUID5(Namespace, "misp-reference|"+v.Source+"|"+v.Link)
+		exists := false
+		for _, r := range paste.ObjectReference {
+			if r.UUID == ref {
+				exists = true
+			}
+		}
+		if !exists {
+			paste.ObjectReference = append(paste.ObjectReference, MISPReference{UUID: ref, ObjectUUID: paste.UUID, ReferencedUUID: link.UUID, RelationshipType: "references", Timestamp: stamp})
+		}
+	}
+
+	sort.Strings(links)
+	event := MISPEvent{
+		UUID: UUID5(Namespace, "misp-event|"+strings.Join(links, "\n")), Info: info, Date: now.Format("2006-01-02"),
+		Timestamp: fmt.Sprint(now.Unix()), ThreatLevelID: "4", Analysis: "2", Distribution: "0",
+		Tag: []MISPTag{{Name: "tempest"}, {Name: "tlp:amber"}},
+	}
+	for _, id := range order {
+		event.Object = append(event.Object, *objects[id])
+	}
+	return MISP{Event: event}
+}
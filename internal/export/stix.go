@@ -0,0 +1,142 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package export
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// stixNamespace is the UUIDv5 namespace (00abedb4-aa42-466c-9c01-fed23315a9b7) defined by the STIX 2.1 specification
+// for deterministic SCO ids
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// stixTime is the timestamp format required by STIX 2.1
+const stixTime = "2006-01-02T15:04:05.000Z"
+
+// STIXObject represents a STIX 2.1 object. Properties are kept in a map, as each object type has its own set of
+// (custom) properties.
+type STIXObject map[string]interface{}
+
+// STIXBundle represents a STIX 2.1 bundle
+type STIXBundle struct {
+	Type    string       `json:"type"`
+	ID      string       `json:"id"`
+	Objects []STIXObject `json:"objects"`
+}
+
+// stixURL returns the deterministic id of a url observable, derived from its value as defined by the specification
+func stixURL(value string) string {
+	contributing, _ := json.Marshal(map[string]string{"value": value})
+	return "url--" + UUID5(stixNamespace, string(contributing))
+}
+
+// STIX converts entries into a STIX 2.1 bundle. Source pastes and links become url observables, every link also
+// becomes an indicator carrying the entry metadata as custom (x_tempest_) properties, and "references" relationships
+// link each paste to the links found within it. Ids are deterministic, so exporting the same entries twice produces
+// the same objects.
+func STIX(entries []models.Entry) STIXBundle {
+	now := time.Now().UTC()
+	identity := "identity--" + UUID5(Namespace, "identity|tempest")
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Format(stixTime)
+
+	objects := []STIXObject{{
+		"type": "identity", "spec_version": "2.1", "id": identity, "created": created, "modified": created,
+		"name": "Tempest", "identity_class": "system",
+	}}
+	seen := make(map[string]bool)
+	var ids []string
+	add := func(o STIXObject) {
+		id := o["id"].(string)
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		objects = append(objects, o)
+	}
+
+	for _, v := range entries {
+		ts := timestamp(v, now).Format(stixTime)
+		linkID := stixURL(v.Link)
+		link := STIXObject{"type": "url", "spec_version": "2.1", "id": linkID, "value": v.Link}
+		for k, val := range metadata(v) {
+			link["x_tempest_"+k] = val
+		}
+		add(link)
+
+		name := v.Title
+		if name == "" {
+			name = v.Link
+		}
+		indicator := STIXObject{
+			"type": "indicator", "spec_version": "2.1", "id": "indicator--" + UUID5(Namespace, "indicator|"+v.Link),
+			"created_by_ref": identity, "created": ts, "modified": ts, "valid_from": ts,
+			"name": name, "indicator_types": []string{"unknown"},
+			"pattern": "[url:value = '" + escapePattern(v.Link) + "']", "pattern_type": "stix",
+			"labels": []string{"tempest"},
+		}
+		if v.Service != "" {
+			indicator["labels"] = []string{"tempest", v.Service}
+		}
+		if v.Description != "" {
+			indicator["description"] = v.Description
+		}
+		for k, val := range metadata(v) {
+			indicator["x_tempest_"+k] = val
+		}
+		add(indicator)
+		add(STIXObject{
+			"type": "relationship", "spec_version": "2.1", "id": "relationship--" + UUID5(Namespace, "based-on|"+v.Link),
+			"created_by_ref": identity, "created": ts, "modified": ts,
+			"relationship_type": "based-on", "source_ref": indicator["id"], "target_ref": linkID,
+		})
+
+		if v.Source == "" {
+			continue
+		}
+		sourceID := stixURL(v.Source)
+		add(STIXObject{"type": "url", "spec_version": "2.1", "id": sourceID, "value": v.Source, "x_tempest_paste": true})
+		add(STIXObject{
+			"type": "relationship", "spec_version": "2.1", "id": "relationship--" + UUID5(Namespace, "references|"+v.Source+"|"+v.Link),
+			"created_by_ref": identity, "created": ts, "modified": ts,
+			"relationship_type": "references", "source_ref": sourceID, "target_ref": linkID,
+			"description": "Paste references link",
+		})
+	}
+
+	sort.Strings(ids)
+	idsJSON, _ := json.Marshal(ids)
+	return STIXBundle{Type: "bundle", ID: "bundle--" + UUID5(Namespace, "bundle|"+string(idsJSON)), Objects: objects}
+}
+
+// escapePattern escapes a string for use within a quoted STIX pattern value
+func escapePattern(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r == '\\' || r == '\'' {
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -19,6 +19,7 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package globals
 
 import (
+	"database/sql"
 	"encoding/csv"
 	"math/rand"
 	"os"
@@ -35,9 +36,15 @@ var DebugFlag bool
 // Mode store the specified output mode
 var Mode string
 
+// JSONFormat stores the JSON output format (ndjson or array)
+var JSONFormat string = "ndjson"
+
 // Filename stores the output file name
 var Filename string
 
+// HarvestFile stores the file that the unhandled host report is written to, harvesting is disabled if empty
+var HarvestFile string
+
 // Wg is a sync.WaitGroup that implements a counter, used in run() for graceful cleanup
 var Wg models.WaitGroupCount = models.WaitGroupCount{}
 
@@ -53,6 +60,9 @@ var Csvfile *os.File = nil
 // Writer is used in writing to Csvfile
 var Writer *csv.Writer = nil
 
+// DB is the opened SQLite database where results are written
+var DB *sql.DB = nil
+
 // Src is used by the TrueRand function
 var Src = rand.NewSource(time.Now().UnixNano())
 
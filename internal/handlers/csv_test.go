@@ -0,0 +1,141 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// entry is an entry with every field set, including values that need quoting
+var entry = models.Entry{Source: "https://rentry.co/abcde/raw", Link: "https://mega.nz/folder/abc#def", Seen: "2023-01-02T03:04:05Z", Title: "a, \"quoted\"\ntitle", Description: "desc", Service: "Mega", Uploaded: "2023", Mtime: "1", Duration: "01:02", Type: "Folder", MimeType: "video/mp4", Size: "1.00 GB", FileCount: 2, Owner: "owner", Resolution: "1920x1080", Direct: "https://direct", Thumbnail: "https://thumb", Downloads: 3, Views: 4, Members: 5, Hash: "hash", AltHash: "alt", Malware: "pass", Trackers: []string{"udp://a", "udp://b"}, Children: []models.Child{{Name: "a.txt", Type: "File", Size: "1 B"}}, Obfuscation: "defanged", Redirects: []string{"https://bit.ly/x", "https://mega.nz/folder/abc#def"}, Status: "Online"}
+
+func TestCSVRow(t *testing.T) {
+	row := CSVRow(entry)
+	if len(row) != len(CSVHeaders) {
+		t.Fatalf("len(CSVRow()) = %d, want %d", len(row), len(CSVHeaders))
+	}
+	// The columns of the original release must keep their positions
+	legacy := []string{"source", "link", "title", "description", "service", "uploaded", "mtime", "type", "size", "filecount", "thumbnail", "downloads", "views", "hash", "malware"}
+	if !slices.Equal(CSVHeaders[:len(legacy)], legacy) {
+		t.Errorf("CSVHeaders = %v, want it to start with %v", CSVHeaders, legacy)
+	}
+}
+
+func TestEncodeParseCSV(t *testing.T) {
+	data, err := EncodeCSV([]models.Entry{entry})
+	if err != nil {
+		t.Fatalf("EncodeCSV() error = %v", err)
+	}
+	entries, err := ParseCSV(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("ParseCSV() = %d entries, want 1", len(entries))
+	}
+	got, want := CSVRow(entries[0]), CSVRow(entry)
+	if !slices.Equal(got, want) {
+		t.Errorf("round trip = %q, want %q", got, want)
+	}
+}
+
+func TestParseCSVHostile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		entries int
+		err     bool
+	}{
+		{"empty", "", 0, false},
+		{"no link column", "source,title\na,b\n", 0, true},
+		{"short rows", "source,title,link\na\n\"b\",c\n", 2, false},
+		{"repeated header", "link,title\nlink,title\nhttps://a,b\n", 1, false},
+		{"invalid children", "link,children\nhttps://a,{not json\n", 1, false},
+		{"unterminated quote", "link\n\"https://a\n", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ParseCSV(strings.NewReader(tt.data))
+			if (err != nil) != tt.err || len(entries) != tt.entries {
+				t.Errorf("ParseCSV() = %d entries, %v, want %d entries, error %v", len(entries), err, tt.entries, tt.err)
+			}
+		})
+	}
+}
+
+func TestOpenCSV(t *testing.T) {
+	dir := t.TempDir()
+
+	// New files get the header row
+	name := filepath.Join(dir, "new.csv")
+	f, migrated, err := OpenCSV(name)
+	if err != nil || migrated {
+		t.Fatalf("OpenCSV() = %v, %v", migrated, err)
+	}
+	f.Close()
+	data, _ := os.ReadFile(name)
+	if header, _ := csv.NewReader(bytes.NewReader(data)).Read(); !slices.Equal(header, CSVHeaders) {
+		t.Errorf("header = %v, want %v", header, CSVHeaders)
+	}
+
+	// Files with the current header are left untouched
+	f, migrated, err = OpenCSV(name)
+	if err != nil || migrated {
+		t.Fatalf("OpenCSV() = %v, %v", migrated, err)
+	}
+	f.Close()
+
+	// Files written by older versions are migrated, keeping a backup
+	name = filepath.Join(dir, "old.csv")
+	old := "source,link,title,description,service,uploaded,mtime,type,size,filecount,thumbnail,downloads,views,hash,malware\n" +
+		"https://rentry.co/a/raw,https://gofile.io/d/abcdef,Title,,Gofile,,,Folder,1 GB,3,,0,0,,\n"
+	if err := os.WriteFile(name, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, migrated, err = OpenCSV(name)
+	if err != nil || !migrated {
+		t.Fatalf("OpenCSV() = %v, %v, want migration", migrated, err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(CSVRow(entry))
+	w.Flush()
+	f.Close()
+
+	if bak, _ := os.ReadFile(name + ".bak"); string(bak) != old {
+		t.Errorf("backup = %q, want %q", bak, old)
+	}
+	data, _ = os.ReadFile(name)
+	entries, err := ParseCSV(bytes.NewReader(data))
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ParseCSV() = %d entries, %v, want 2", len(entries), err)
+	}
+	if entries[0].Link != "https://gofile.io/d/abcdef" || entries[0].FileCount != 3 || entries[0].Size != "1 GB" {
+		t.Errorf("migrated entry = %+v", entries[0])
+	}
+	if !slices.Equal(CSVRow(entries[1]), CSVRow(entry)) {
+		t.Errorf("appended entry = %+v", entries[1])
+	}
+}
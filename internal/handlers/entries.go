@@ -0,0 +1,366 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// JSON output formats
+const (
+	NDJSON    = "ndjson" // One JSON encoded entry per line
+	JSONArray = "array"  // A single JSON array, kept valid after every write
+)
+
+// JSON file layouts recognized by JSONLayout
+const (
+	LayoutEmpty  = "empty"  // The file is empty/does not exist
+	LayoutNDJSON = "ndjson" // One entry per line
+	LayoutArray  = "array"  // A JSON array of entries
+	LayoutClean  = "clean"  // {"content":[...]} written by older versions of the clean command
+	LayoutLegacy = "legacy" // {...},\n lines written by older versions of Tempest
+)
+
+// arrayClose is the closing bracket of a JSON array file, overwritten by every write
+const arrayClose = "\n]\n"
+
+// arrayEntries is the number of entries in the opened JSON array file
+var arrayEntries int
+
+// JSONLayout identifies the layout of the contents of a JSON file written by Tempest
+func JSONLayout(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0:
+		return LayoutEmpty
+	case trimmed[0] == '[':
+		return LayoutArray
+	case bytes.HasPrefix(bytes.Join(bytes.Fields(trimmed), nil), []byte(`{"content":`)):
+		return LayoutClean
+	case bytes.HasSuffix(trimmed, []byte("},")) || bytes.Contains(trimmed, []byte("},\n")):
+		return LayoutLegacy
+	default:
+		return LayoutNDJSON
+	}
+}
+
+// ParseJSON decodes the entries contained within the contents of a JSON file written by Tempest, regardless of its
+// layout
+func ParseJSON(data []byte) ([]models.Entry, error) {
+	var entries []models.Entry
+	switch JSONLayout(data) {
+	case LayoutEmpty:
+		return nil, nil
+	case LayoutArray:
+		err := json.Unmarshal(data, &entries)
+		return entries, err
+	case LayoutClean:
+		var clean struct {
+			Content []models.Entry `json:"content"`
+		}
+		err := json.Unmarshal(data, &clean)
+		return clean.Content, err
+	}
+
+	// NDJSON and legacy files hold a single entry per line, legacy lines end with a comma
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ",")
+		if text == "" {
+			continue
+		}
+		var v models.Entry
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return entries, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, v)
+	}
+	return entries, scanner.Err()
+}
+
+// ParseCSV decodes the entries contained within a CSV file written by Tempest. Columns are matched by the header row,
+// so files written by older versions (with fewer columns) can be read too.
+func ParseCSV(r io.Reader) ([]models.Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	col := make(map[string]int)
+	for i, h := range records[0] {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["link"]; !ok {
+		return nil, errors.New("csv file does not contain a link column")
+	}
+
+	var entries []models.Entry
+	for _, record := range records[1:] {
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		// Skip header rows repeated within the file
+		if get("link") == "link" {
+			continue
+		}
+		num := func(name string) int {
+			n, _ := strconv.Atoi(get(name))
+			return n
+		}
+		v := models.Entry{Source: get("source"), Link: get("link"), Seen: get("seen"), Title: get("title"), Description: get("description"), Service: get("service"), Uploaded: get("uploaded"), Mtime: get("mtime"), Duration: get("duration"), Type: get("type"), MimeType: get("mimetype"), Size: get("size"), FileCount: num("filecount"), Owner: get("owner"), Resolution: get("resolution"), Direct: get("direct"), Thumbnail: get("thumbnail"), Downloads: num("downloads"), Views: num("views"), Members: num("members"), Hash: get("hash"), AltHash: get("althash"), Malware: get("malware"), Trackers: strings.Fields(get("trackers")), Obfuscation: get("obfuscation"), Redirects: strings.Fields(get("redirects")), Status: get("status")}
+		if children := get("children"); children != "" {
+			if err := json.Unmarshal([]byte(children), &v.Children); err != nil {
+				LogErr(err, "failed to unmarshal children of "+v.Link)
+			}
+		}
+		entries = append(entries, v)
+	}
+	return entries, nil
+}
+
+// ReadEntries reads the entries contained within a JSON (any layout) or CSV file written by Tempest. The format is
+// chosen by the file extension.
+func ReadEntries(path string) ([]models.Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ParseCSV(bytes.NewReader(data))
+	}
+	return ParseJSON(data)
+}
+
+// EncodeJSON encodes entries in the specified JSON output format (NDJSON or JSONArray)
+func EncodeJSON(entries []models.Entry, format string) ([]byte, error) {
+	if format != NDJSON && format != JSONArray {
+		return nil, fmt.Errorf("unknown json format %q (expected ndjson or array)", format)
+	}
+	var buf bytes.Buffer
+	if format == JSONArray {
+		buf.WriteString("[")
+	}
+	for i, v := range entries {
+		vByte, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if format == JSONArray {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.Write(vByte)
+		if format != JSONArray {
+			buf.WriteString("\n")
+		}
+	}
+	if format == JSONArray {
+		buf.WriteString(arrayClose)
+	}
+	return buf.Bytes(), nil
+}
+
+// OpenJSON opens (creating if necessary) the JSON output file in the specified format. Existing files written in an
+// older layout or in the other format are migrated once, keeping a copy of the original file with a .bak suffix. It
+// returns whether a migration took place.
+func OpenJSON(filename, format string) (*os.File, bool, error) {
+	if format != NDJSON && format != JSONArray {
+		return nil, false, fmt.Errorf("unknown json format %q (expected ndjson or array)", format)
+	}
+	migrated := false
+	data, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, false, err
+	}
+
+	layout := JSONLayout(data)
+	if layout != LayoutEmpty && layout != format {
+		entries, err := ParseJSON(data)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to migrate %s: %w", filename, err)
+		}
+		converted, err := EncodeJSON(entries, format)
+		if err != nil {
+			return nil, false, err
+		}
+		if err = os.WriteFile(filename+".bak", data, 0600); err != nil {
+			return nil, false, err
+		}
+		if err = os.WriteFile(filename, converted, 0600); err != nil {
+			return nil, false, err
+		}
+		data = converted
+		migrated = true
+	}
+
+	if format != JSONArray {
+		file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+		return file, migrated, err
+	}
+
+	// Array files are written in place, so they are opened without O_APPEND and normalized to end with arrayClose
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, migrated, err
+	}
+	entries, err := ParseJSON(data)
+	if err != nil {
+		file.Close()
+		return nil, migrated, err
+	}
+	arrayEntries = len(entries)
+	if !bytes.HasSuffix(data, []byte(arrayClose)) {
+		normalized, err := EncodeJSON(entries, JSONArray)
+		if err == nil {
+			err = file.Truncate(0)
+		}
+		if err == nil {
+			_, err = file.WriteAt(normalized, 0)
+		}
+		if err != nil {
+			file.Close()
+			return nil, migrated, err
+		}
+	}
+	return file, migrated, nil
+}
+
+// EncodeCSV encodes entries as a CSV file, starting with the CSVHeaders row
+func EncodeCSV(entries []models.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(CSVHeaders); err != nil {
+		return nil, err
+	}
+	for _, v := range entries {
+		if err := w.Write(CSVRow(v)); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// OpenCSV opens (creating if necessary) the CSV output file, writing the header row to new files. Existing files
+// with a different header (e.g. written by an older version, with fewer or differently ordered columns) are migrated
+// once, keeping a copy of the original file with a .bak suffix. It returns whether a migration took place.
+func OpenCSV(filename string) (*os.File, bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, false, err
+	}
+
+	migrated := false
+	header, _ := csv.NewReader(bytes.NewReader(data)).Read()
+	if len(bytes.TrimSpace(data)) == 0 || !slices.Equal(header, CSVHeaders) {
+		var entries []models.Entry
+		if len(bytes.TrimSpace(data)) > 0 {
+			entries, err = ParseCSV(bytes.NewReader(data))
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to migrate %s: %w", filename, err)
+			}
+			if err = os.WriteFile(filename+".bak", data, 0600); err != nil {
+				return nil, false, err
+			}
+			migrated = true
+		}
+		converted, err := EncodeCSV(entries)
+		if err != nil {
+			return nil, false, err
+		}
+		if err = os.WriteFile(filename, converted, 0600); err != nil {
+			return nil, false, err
+		}
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	return file, migrated, err
+}
+
+// writeJSON writes a single entry to the opened JSON file in the configured format. In array mode the closing bracket
+// is overwritten and rewritten in the same write, so the file remains valid JSON after every entry.
+func writeJSON(v models.Entry) error {
+	vByte, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if globals.JSONFormat != JSONArray {
+		_, err = globals.Jsonfile.Write(append(vByte, '\n'))
+		return err
+	}
+
+	offset, err := globals.Jsonfile.Seek(-int64(len(arrayClose)), io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	prefix := "\n"
+	if arrayEntries > 0 {
+		prefix = ",\n"
+	}
+	_, err = globals.Jsonfile.WriteAt([]byte(prefix+string(vByte)+arrayClose), offset)
+	if err != nil {
+		return err
+	}
+	arrayEntries++
+	return nil
+}
+
+// DeduplicateEntries removes identical entries, keeping the first occurrence of each
+func DeduplicateEntries(entries []models.Entry) []models.Entry {
+	seen := make(map[string]bool)
+	var deduped []models.Entry
+	for _, v := range entries {
+		vByte, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		if !seen[string(vByte)] {
+			seen[string(vByte)] = true
+			deduped = append(deduped, v)
+		}
+	}
+	return deduped
+}
@@ -22,12 +22,17 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/internal/sqlite"
 	"github.com/ax-i-om/tempest/pkg/models"
 )
 
@@ -83,6 +88,81 @@ func FixName(str, substr string) string {
 	}
 }
 
+// CSVHeaders are the column names written to the first row of a new CSV file, in the same order as CSVRow. New
+// columns are appended to the end, so rows stay aligned with the header of files written by older versions.
+var CSVHeaders = []string{"source", "link", "title", "description", "service", "uploaded", "mtime", "type", "size", "filecount", "thumbnail", "downloads", "views", "hash", "malware", "seen", "duration", "mimetype", "owner", "resolution", "direct", "members", "althash", "trackers", "children", "obfuscation", "redirects", "status"}
+
+// CSVRow converts an entry into a CSV record. Children are JSON encoded into a single column.
+func CSVRow(v models.Entry) []string {
+	children := ""
+	if len(v.Children) > 0 {
+		cByte, err := json.Marshal(v.Children)
+		if err != nil {
+			LogErr(err, "failed to marshal children to json during csv conversion")
+		} else {
+			children = string(cByte)
+		}
+	}
+	return []string{v.Source, v.Link, v.Title, v.Description, v.Service, v.Uploaded, v.Mtime, v.Type, v.Size, fmt.Sprint(v.FileCount), v.Thumbnail, fmt.Sprint(v.Downloads), fmt.Sprint(v.Views), v.Hash, v.Malware, v.Seen, v.Duration, v.MimeType, v.Owner, v.Resolution, v.Direct, fmt.Sprint(v.Members), v.AltHash, strings.Join(v.Trackers, " "), children, v.Obfuscation, strings.Join(v.Redirects, " "), v.Status}
+}
+
+// FormatBytes converts a byte count into a human readable size string (e.g. 1.50 GB), similar to
+// the sizes scraped from provider pages. A negative byte count returns an empty string.
+func FormatBytes(b int64) string {
+	if b < 0 {
+		return ""
+	}
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// sizeRe matches human readable sizes such as 1.50 GB, 500MB, 12.3 MiB or 1,024 bytes
+var sizeRe *regexp.Regexp = regexp.MustCompile(`(?i)^\s*([\d.,]+)\s*(([KMGTPE])i?B?|B|bytes?)?\s*$`)
+
+// ParseBytes converts a human readable size string (e.g. 1.50 GB) into a byte count, the inverse of FormatBytes.
+// Units are treated as powers of 1024. It returns -1 if the size cannot be parsed.
+func ParseBytes(size string) int64 {
+	m := sizeRe.FindStringSubmatch(size)
+	if m == nil {
+		return -1
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	if err != nil {
+		return -1
+	}
+	if m[3] != "" {
+		n *= math.Pow(1024, float64(strings.Index("KMGTPE", strings.ToUpper(m[3]))+1))
+	}
+	return int64(n)
+}
+
+// FormatDuration converts a number of seconds into a video length string (e.g. 01:02:03, or 02:03 for videos
+// shorter than an hour), matching the format displayed by video hosts.
+func FormatDuration(seconds int) string {
+	if seconds < 3600 {
+		return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// Sink receives every entry passed to Write, in addition to the specified output mode (e.g. a webhook). Send is
+// called concurrently, without holding the write mutex, and must not block.
+type Sink interface {
+	Send(v models.Entry)
+	Close() error
+}
+
+// Sinks contains the registered sinks, they are closed by Wipe
+var Sinks []Sink
+
 // Write is used to write all entries from results to the specified file/output
 func Write(results []models.Entry) {
 	// Loop through all entries in results
@@ -93,38 +173,52 @@ func Write(results []models.Entry) {
 			fmt.Println(v.Service, ": ", v.Link)
 		case "json": // If mode is set to json:
 			LogInfo("starting json write operation")
-			// JSON encode the current iteration's accompanying entry (v)
-			vByte, err := json.Marshal(v)
-			if err != nil {
-				LogErr(err, "failed to marshal entry to json during write operation")
-				continue // immediately start next iteration
-			}
-			// Convert the encoded JSON (type []byte) to the previously opened JSON file.
-			_, err = globals.Jsonfile.WriteString(string(vByte) + ",\n")
+			// JSON encode the current iteration's accompanying entry (v) and write it in the configured format
+			err := writeJSON(v)
 			if err != nil {
 				LogErr(err, "failed to write json to file during write operation")
-				continue // immediately start next iteration
+				break // skip the rest of this entry, the mutex is unlocked below
 			}
 		case "csv": // If mode is set to csv:
 			LogInfo("starting csv write operation")
 			// Create a CSV record based on the current iteration's accompanying entry (v)
-			row := []string{v.Source, v.Link, v.Title, v.Description, v.Service, v.Uploaded, v.Mtime, v.Type, v.Size, fmt.Sprint(v.FileCount), v.Thumbnail, fmt.Sprint(v.Downloads), fmt.Sprint(v.Views), v.Hash, v.Malware}
+			row := CSVRow(v)
 			// Write the record
 			err := globals.Writer.Write(row)
 			if err != nil {
 				LogErr(err, "failed to write CSV row during write operation")
-				continue // immediately start next iteration
+				break // skip the rest of this entry, the mutex is unlocked below
 			}
 			// Call flush to ensure that the record is written to the CSV file
 			globals.Writer.Flush()
 			LogInfo("successfully wrote row to csv file during write operation")
+		case "sqlite": // If mode is set to sqlite:
+			LogInfo("starting sqlite write operation")
+			// Upsert the current iteration's accompanying entry (v), its source and children
+			err := sqlite.Upsert(globals.DB, v)
+			if err != nil {
+				LogErr(err, "failed to upsert entry during write operation")
+				break // skip the rest of this entry, the mutex is unlocked below
+			}
 		}
 		globals.WriteMutex.Unlock()
+		// Pass the entry to every registered sink, outside of the mutex so a slow sink cannot stall the writes
+		for _, s := range Sinks {
+			s.Send(v)
+		}
 	}
 }
 
 // Wipe is used to close and flush all opened files/writers
 func Wipe() {
+	// close all registered sinks, sending any queued entries
+	for _, s := range Sinks {
+		err := s.Close()
+		if err != nil {
+			LogErr(err, "failed to close sink during wipe operation")
+		}
+	}
+	Sinks = nil
 	// if jsonfile was assigned a value other than nil, close it
 	if globals.Jsonfile != nil {
 		err := globals.Jsonfile.Close()
@@ -139,6 +233,15 @@ func Wipe() {
 		globals.Writer.Flush()
 		LogInfo("successfully flushed writer during wipe operation")
 	}
+	// if db was assigned a value other than nil, close it
+	if globals.DB != nil {
+		err := globals.DB.Close()
+		if err != nil {
+			LogErr(err, "failed to close sqlite database during wipe operation")
+		} else {
+			LogInfo("successfully closed sqlite database during wipe operation")
+		}
+	}
 	// if csvfile was assigned a value other than nil, close it
 	if globals.Csvfile != nil {
 		err := globals.Csvfile.Close()
@@ -212,7 +315,35 @@ func GetRes(link string) (*http.Response, error) {
 		return nil, err
 	}
 
+	start := time.Now()
+	res, err := client.Do(req)
+	metrics.ObserveRequest(req.URL.Hostname(), time.Since(start), res, err)
+	if err != nil {
+		if !strings.Contains(err.Error(), "exceeded") {
+			LogErr(err, "failed to send request")
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// HeadRes sends a HEAD request to a specified URL and returns an *http.Response and error that will be nil if successful.
+// It is used to inspect the headers of a resource (e.g. a direct file link) without downloading its body.
+func HeadRes(link string) (*http.Response, error) {
+	client := &http.Client{
+		Timeout: time.Second * 15, // connection timeout after 15 seconds
+	}
+
+	req, err := http.NewRequest(http.MethodHead, link, nil)
+	if err != nil {
+		LogErr(err, "failed to wrap new request")
+		return nil, err
+	}
+
+	start := time.Now()
 	res, err := client.Do(req)
+	metrics.ObserveRequest(req.URL.Hostname(), time.Since(start), res, err)
 	if err != nil {
 		if !strings.Contains(err.Error(), "exceeded") {
 			LogErr(err, "failed to send request")
@@ -0,0 +1,135 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package handlers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestParseJSON(t *testing.T) {
+	a := `{"link":"https://a","service":"Mega"}`
+	b := `{"link":"https://b","title":"x\"y"}`
+	tests := []struct {
+		name, data string
+		layout     string
+		links      []string
+		wantErr    bool
+	}{
+		{"empty", " \n", LayoutEmpty, nil, false},
+		{"ndjson", a + "\n\n" + b + "\n", LayoutNDJSON, []string{"https://a", "https://b"}, false},
+		{"array", "[\n" + a + ",\n" + b + "\n]\n", LayoutArray, []string{"https://a", "https://b"}, false},
+		{"clean", `{ "content": [` + a + `] }`, LayoutClean, []string{"https://a"}, false},
+		{"legacy", a + ",\n" + b + ",\n", LayoutLegacy, []string{"https://a", "https://b"}, false},
+		{"truncated array", "[\n" + a + ",\n", LayoutArray, nil, true},
+		{"garbage line", a + "\nnot json\n", LayoutNDJSON, []string{"https://a"}, true},
+		{"wrong type", `{"link":1}`, LayoutNDJSON, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := JSONLayout([]byte(tt.data)); got != tt.layout {
+				t.Errorf("JSONLayout() = %q, want %q", got, tt.layout)
+			}
+			entries, err := ParseJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var links []string
+			for _, v := range entries {
+				links = append(links, v.Link)
+			}
+			if !reflect.DeepEqual(links, tt.links) {
+				t.Errorf("ParseJSON() links = %q, want %q", links, tt.links)
+			}
+		})
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	entries := []models.Entry{entry, {Link: "https://b", Title: "<b> "}}
+	for _, format := range []string{NDJSON, JSONArray} {
+		data, err := EncodeJSON(entries, format)
+		if err != nil {
+			t.Fatalf("EncodeJSON(%s) error = %v", format, err)
+		}
+		if format == JSONArray && !json.Valid(data) {
+			t.Errorf("EncodeJSON(%s) is not valid JSON:\n%s", format, data)
+		}
+		if got := JSONLayout(data); got != format {
+			t.Errorf("JSONLayout(EncodeJSON(%s)) = %q", format, got)
+		}
+		parsed, err := ParseJSON(data)
+		if err != nil || !reflect.DeepEqual(parsed, entries) {
+			t.Errorf("ParseJSON(EncodeJSON(%s)) = %+v, %v, want %+v", format, parsed, err, entries)
+		}
+	}
+	if _, err := EncodeJSON(entries, "xml"); err == nil {
+		t.Error("EncodeJSON() accepted an unknown format")
+	}
+}
+
+func TestOpenJSON(t *testing.T) {
+	defer func(format string) { globals.JSONFormat = format }(globals.JSONFormat)
+	filename := filepath.Join(t.TempDir(), "results.json")
+	original := `{"link":"https://a"},` + "\n"
+	if err := os.WriteFile(filename, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := OpenJSON(filename, "xml"); err == nil {
+		t.Fatal("OpenJSON() accepted an unknown format")
+	}
+
+	file, migrated, err := OpenJSON(filename, JSONArray)
+	if err != nil || !migrated {
+		t.Fatalf("OpenJSON() = %v, %v, want a migration", migrated, err)
+	}
+	backup, _ := os.ReadFile(filename + ".bak")
+	if string(backup) != original {
+		t.Errorf("backup = %q, want %q", backup, original)
+	}
+
+	// Every write keeps the array valid
+	globals.JSONFormat, globals.Jsonfile = JSONArray, file
+	if err = writeJSON(models.Entry{Link: "https://b"}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	data, _ := os.ReadFile(filename)
+	entries, err := ParseJSON(data)
+	if !json.Valid(data) || err != nil || len(entries) != 2 || entries[1].Link != "https://b" {
+		t.Errorf("array file after write = %q (%v)", data, err)
+	}
+
+	// Reopening in the same format does not migrate
+	file, migrated, err = OpenJSON(filename, JSONArray)
+	if err != nil || migrated {
+		t.Errorf("OpenJSON() = %v, %v, want no migration", migrated, err)
+	}
+	file.Close()
+	if data, _ := os.ReadFile(filename); strings.Count(string(data), "https://") != 2 {
+		t.Errorf("reopened file = %q", data)
+	}
+}
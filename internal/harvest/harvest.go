@@ -0,0 +1,163 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package harvest records links to hosts that are not handled by any registered provider, so that the most common
+// unsupported hosts can be identified.
+package harvest
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/providers"
+)
+
+// Compile RegEx expressions for extraction of links
+var uLink *regexp.Regexp = regexp.MustCompile(`(https|http)://[^\s"'<>)\]]+`)
+
+// MaxSamples is the maximum number of sample pastes and links kept per domain
+var MaxSamples = 5
+
+// MaxDomains is the maximum number of domains tracked. Once exceeded, the long tail is dropped: only the most seen
+// half of the domains is kept, so memory stays bounded during long runs.
+var MaxDomains = 10000
+
+// Domain represents an unhandled host and the number of times it was seen
+type Domain struct {
+	Domain string   `json:"domain"`
+	Count  int      `json:"count"`
+	Pastes []string `json:"pastes"`
+	Links  []string `json:"links"`
+}
+
+// Report represents the contents of a harvest report file
+type Report struct {
+	Generated string   `json:"generated"`
+	Pruned    int      `json:"pruned"` // Domains dropped from the long tail, their counts are not included
+	Domains   []Domain `json:"domains"`
+}
+
+var mu sync.Mutex
+var domains = make(map[string]*Domain)
+var pruned int
+
+// Record extracts every link contained within the contents of a paste and tallies those whose host is not handled by
+// a registered provider. Links pointing back to the paste site itself are ignored.
+func Record(contents, source string) {
+	sourceHost := ""
+	if u, err := url.Parse(source); err == nil {
+		sourceHost = Normalize(u.Hostname())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, link := range uLink.FindAllString(contents, -1) {
+		u, err := url.Parse(link)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		host := Normalize(u.Hostname())
+		if host == sourceHost || providers.Handles(host) {
+			continue
+		}
+		d, ok := domains[host]
+		if !ok {
+			if len(domains) >= MaxDomains {
+				prune()
+			}
+			d = &Domain{Domain: host}
+			domains[host] = d
+		}
+		d.Count++
+		d.Pastes = addSample(d.Pastes, source)
+		d.Links = addSample(d.Links, link)
+	}
+}
+
+// Normalize lowercases a host and strips any leading www.
+func Normalize(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// Domains returns every recorded domain, sorted by count (descending)
+func Domains() []Domain {
+	mu.Lock()
+	defer mu.Unlock()
+	return sorted()
+}
+
+// Pruned returns the number of domains dropped from the long tail since the start
+func Pruned() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return pruned
+}
+
+// sorted returns every recorded domain, sorted by count (descending). The caller must hold mu.
+func sorted() []Domain {
+	var list []Domain
+	for _, d := range domains {
+		list = append(list, *d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Domain < list[j].Domain
+	})
+	return list
+}
+
+// prune drops the least seen half of the recorded domains. A dropped domain that is seen again is counted from
+// scratch. The caller must hold mu.
+func prune() {
+	list := sorted()
+	for _, d := range list[min(MaxDomains/2, len(list)):] {
+		delete(domains, d.Domain)
+		pruned++
+	}
+}
+
+// Write writes a JSON report of every recorded domain to the specified file, overwriting it if it exists
+func Write(filename string) error {
+	report := Report{Generated: time.Now().UTC().Format(time.RFC3339), Pruned: Pruned(), Domains: Domains()}
+	b, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0600)
+}
+
+// addSample appends v to samples if it isn't already present and there are fewer than MaxSamples
+func addSample(samples []string, v string) []string {
+	if len(samples) >= MaxSamples {
+		return samples
+	}
+	for _, s := range samples {
+		if s == v {
+			return samples
+		}
+	}
+	return append(samples, v)
+}
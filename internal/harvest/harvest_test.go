@@ -0,0 +1,64 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package harvest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRecordPrunes(t *testing.T) {
+	defer func(n int) { MaxDomains = n }(MaxDomains)
+	MaxDomains = 4
+	mu.Lock()
+	domains, pruned = make(map[string]*Domain), 0
+	mu.Unlock()
+
+	// popular.test is seen often, the others once each
+	var contents []string
+	for i := 0; i < 3; i++ {
+		contents = append(contents, "https://popular.test/x")
+	}
+	for i := 0; i < 10; i++ {
+		contents = append(contents, fmt.Sprintf("https://tail%d.test/x", i))
+	}
+	Record(strings.Join(contents, "\n"), "https://rentry.co/abcde/raw")
+
+	list := Domains()
+	if len(list) > MaxDomains {
+		t.Errorf("%d domains tracked, want at most %d", len(list), MaxDomains)
+	}
+	if len(list) == 0 || list[0].Domain != "popular.test" || list[0].Count != 3 {
+		t.Errorf("Domains() = %+v, want popular.test first with a count of 3", list)
+	}
+	if Pruned()+len(list) != 11 {
+		t.Errorf("Pruned() = %d with %d domains tracked, want 11 in total", Pruned(), len(list))
+	}
+}
+
+func TestRecordIgnoresHandled(t *testing.T) {
+	mu.Lock()
+	domains, pruned = make(map[string]*Domain), 0
+	mu.Unlock()
+	Record("https://mega.nz/file/a https://rentry.co/other https://WWW.Unknown.test/a https://unknown.test/b", "https://rentry.co/abcde/raw")
+	list := Domains()
+	if len(list) != 1 || list[0].Domain != "unknown.test" || list[0].Count != 2 || len(list[0].Links) != 2 {
+		t.Errorf("Domains() = %+v, want unknown.test with a count of 2", list)
+	}
+}
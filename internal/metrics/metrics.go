@@ -0,0 +1,373 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package metrics contains the counters, gauges and histograms describing Tempest's throughput and hit rate, and
+// serves them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+)
+
+// Addr is the address the standalone metrics server listens on (set by the --metrics flag), disabled if empty
+var Addr string
+
+// Validation outcomes
+const (
+	Valid   = "valid"   // The link was valid and an entry was returned
+	Removed = "removed" // An entry was returned for the link, but its content was removed (e.g. following a DMCA notice)
+	Invalid = "invalid" // The link was extracted but no entry was returned (invalid, offline or failed validation)
+	Failed  = "error"   // The delegation itself failed
+)
+
+// DurationBuckets are the upper bounds (in seconds) of the request latency histogram buckets
+var DurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15}
+
+// metric is implemented by every metric type, so that they can be written in order
+type metric interface {
+	write(w io.Writer)
+}
+
+// Vec holds the values of a counter or gauge, keyed by label values
+type Vec struct {
+	name, help, kind string
+	labels           []string
+	mu               sync.Mutex
+	values           map[string]float64
+}
+
+// Histogram holds the observations of a histogram, keyed by label values
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*series
+}
+
+// series holds the observations of a single histogram series
+type series struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// gaugeFunc is a gauge whose value is read when the metrics are written
+type gaugeFunc struct {
+	name, help string
+	value      func() float64
+}
+
+// Metrics exposed by Tempest
+var (
+	Attempts        = newVec("tempest_paste_attempts_total", "Random paste URLs requested.", "counter", "source")
+	Hits            = newVec("tempest_paste_hits_total", "Random paste URLs that existed (200 response).", "counter", "source")
+	Entries         = newVec("tempest_entries_total", "Entries found, by service.", "counter", "service")
+	Validations     = newVec("tempest_validations_total", "Outcomes of link validation (one per extracted link), by provider.", "counter", "provider", "outcome")
+	HTTPErrors      = newVec("tempest_http_errors_total", "Failed HTTP requests, by category (excluding 404 responses from paste sites).", "counter", "category")
+	RequestDuration = &Histogram{name: "tempest_request_duration_seconds", help: "Latency of HTTP requests, by registered host (other for unregistered hosts).", labels: []string{"host"}, buckets: DurationBuckets, series: make(map[string]*series)}
+	InFlight        = &gaugeFunc{name: "tempest_inflight_workers", help: "Worker goroutines currently running.", value: func() float64 { return float64(globals.Wg.GetCount()) }}
+	started         = time.Now()
+	Uptime          = &gaugeFunc{name: "tempest_uptime_seconds", help: "Seconds since Tempest started.", value: func() float64 { return time.Since(started).Seconds() }}
+)
+
+// all contains every metric, in the order they are written
+var all = []metric{Attempts, Hits, Entries, Validations, HTTPErrors, RequestDuration, InFlight, Uptime}
+
+// newVec returns an empty counter/gauge vector
+func newVec(name, help, kind string, labels ...string) *Vec {
+	return &Vec{name: name, help: help, kind: kind, labels: labels, values: make(map[string]float64)}
+}
+
+// Add adds a value to the series identified by the label values, which must be passed in the order of the labels
+func (v *Vec) Add(value float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[strings.Join(labelValues, "\xff")] += value
+}
+
+// Inc adds 1 to the series identified by the label values
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Snapshot returns the value of every series, keyed by its label values joined with a comma
+func (v *Vec) Snapshot() map[string]float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	snap := make(map[string]float64, len(v.values))
+	for k, val := range v.values {
+		snap[strings.ReplaceAll(k, "\xff", ",")] = val
+	}
+	return snap
+}
+
+// Total returns the sum of every series
+func (v *Vec) Total() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	total := 0.0
+	for _, val := range v.values {
+		total += val
+	}
+	return total
+}
+
+// Observe records an observation in the series identified by the label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := strings.Join(labelValues, "\xff")
+	s, ok := h.series[key]
+	if !ok {
+		s = &series{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, b := range h.buckets {
+		if value <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// OtherHost is the host label of requests to hosts that were not registered
+const OtherHost = "other"
+
+// hosts contains the registered hosts. Hosts are taken from paste contents (e.g. while following redirects), so only
+// registered hosts are used as labels, keeping the number of series bounded.
+var hosts = struct {
+	sync.RWMutex
+	known   map[string]bool
+	sources map[string]bool // Paste sites, their 404 responses are random paste misses rather than errors
+}{known: make(map[string]bool), sources: make(map[string]bool)}
+
+// RegisterHosts registers hosts (e.g. the hosts handled by the providers) used as request duration labels.
+// Subdomains are labelled with the registered host.
+func RegisterHosts(names ...string) {
+	hosts.Lock()
+	defer hosts.Unlock()
+	for _, h := range names {
+		hosts.known[strings.ToLower(h)] = true
+	}
+}
+
+// RegisterSourceHosts registers the hosts of paste sites. They are used as request duration labels, and their 404
+// responses are not counted as errors.
+func RegisterSourceHosts(names ...string) {
+	RegisterHosts(names...)
+	hosts.Lock()
+	defer hosts.Unlock()
+	for _, h := range names {
+		hosts.sources[strings.ToLower(h)] = true
+	}
+}
+
+// HostLabel returns the registered host a host belongs to (the host itself or a parent domain), or OtherHost if it
+// was not registered
+func HostLabel(host string) string {
+	hosts.RLock()
+	defer hosts.RUnlock()
+	h := strings.TrimSuffix(strings.ToLower(host), ".")
+	for h != "" {
+		if hosts.known[h] {
+			return h
+		}
+		_, parent, ok := strings.Cut(h, ".")
+		if !ok {
+			break
+		}
+		h = parent
+	}
+	return OtherHost
+}
+
+// isSource reports whether a host label is a registered paste site
+func isSource(label string) bool {
+	hosts.RLock()
+	defer hosts.RUnlock()
+	return hosts.sources[label]
+}
+
+// ObserveRequest records the latency of a request to a host, and its failure category (if any). 404 responses from
+// paste sites are not counted as errors, as most randomly generated paste URLs do not exist.
+func ObserveRequest(host string, elapsed time.Duration, res *http.Response, err error) {
+	label := HostLabel(host)
+	RequestDuration.Observe(elapsed.Seconds(), label)
+	if err != nil {
+		HTTPErrors.Inc(Classify(err))
+	} else if c := ClassifyStatus(res.StatusCode); c != "" && !(c == "status_404" && isSource(label)) {
+		HTTPErrors.Inc(c)
+	}
+}
+
+// Classify returns the category of a request error: timeout, dns, refused, reset, tls or other
+func Classify(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var authErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "reset"
+	case errors.As(err, &certErr), errors.As(err, &authErr), errors.As(err, &hostErr), errors.As(err, &invalidErr), errors.As(err, &recordErr):
+		return "tls"
+	default:
+		return "other"
+	}
+}
+
+// ClassifyStatus returns the category of an HTTP status code that indicates a failure (status_404, status_429,
+// status_4xx or status_5xx), or an empty string if it does not indicate a failure
+func ClassifyStatus(code int) string {
+	switch {
+	case code == http.StatusNotFound:
+		return "status_404"
+	case code == http.StatusTooManyRequests:
+		return "status_429"
+	case code >= 500:
+		return "status_5xx"
+	case code >= 400:
+		return "status_4xx"
+	default:
+		return ""
+	}
+}
+
+// Write writes every metric in the Prometheus text exposition format
+func Write(w io.Writer) {
+	for _, m := range all {
+		m.write(w)
+	}
+}
+
+// Handler serves the metrics in the Prometheus text exposition format
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	Write(w)
+}
+
+// Mux returns a handler serving the metrics at /metrics
+func Mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", Handler)
+	return mux
+}
+
+// write implements metric
+func (v *Vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	for _, k := range sortedKeys(v.values) {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, labelString(v.labels, strings.Split(k, "\xff"), "", ""), formatFloat(v.values[k]))
+	}
+}
+
+// write implements metric
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, k := range sortedKeys(h.series) {
+		s, values := h.series[k], strings.Split(k, "\xff")
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, values, "le", formatFloat(b)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelString(h.labels, values, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelString(h.labels, values, "", ""), s.count)
+	}
+}
+
+// write implements metric
+func (g *gaugeFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.value()))
+}
+
+// labelString formats label names and values as {name="value",...}, optionally followed by an extra label
+func labelString(names, values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, n := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		pairs = append(pairs, n+`="`+escape(v)+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escape escapes a label value as required by the text exposition format
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// formatFloat formats a sample value
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
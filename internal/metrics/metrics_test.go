@@ -0,0 +1,153 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHostLabel(t *testing.T) {
+	RegisterHosts("files.example", "short.example")
+	RegisterSourceHosts("paste.example")
+	tests := []struct {
+		host, want string
+	}{
+		{"files.example", "files.example"},
+		{"www.files.example", "files.example"},
+		{"a.b.FILES.example.", "files.example"},
+		{"paste.example", "paste.example"},
+		{"files.example.attacker.test", OtherHost},
+		{"notfiles.example", OtherHost},
+		{"random-1234.test", OtherHost},
+		{"", OtherHost},
+	}
+	for _, tt := range tests {
+		if got := HostLabel(tt.host); got != tt.want {
+			t.Errorf("HostLabel(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	RegisterHosts("errors.example")
+	RegisterSourceHosts("misses.example")
+	res := func(code int) *http.Response { return &http.Response{StatusCode: code} }
+
+	before := HTTPErrors.Snapshot()["status_404"]
+	ObserveRequest("misses.example", time.Millisecond, res(http.StatusNotFound), nil)
+	if got := HTTPErrors.Snapshot()["status_404"]; got != before {
+		t.Errorf("404 from a paste site counted as an error (%v -> %v)", before, got)
+	}
+	ObserveRequest("www.errors.example", time.Millisecond, res(http.StatusNotFound), nil)
+	if got := HTTPErrors.Snapshot()["status_404"]; got != before+1 {
+		t.Errorf("404 from a provider host not counted (%v -> %v)", before, got)
+	}
+	before5xx := HTTPErrors.Snapshot()["status_5xx"]
+	ObserveRequest("misses.example", time.Millisecond, res(http.StatusBadGateway), nil)
+	if got := HTTPErrors.Snapshot()["status_5xx"]; got != before5xx+1 {
+		t.Errorf("5xx from a paste site not counted (%v -> %v)", before5xx, got)
+	}
+
+	// Unregistered hosts share a single series
+	for i := 0; i < 50; i++ {
+		ObserveRequest(fmt.Sprintf("host%d.attacker.test", i), time.Millisecond, res(http.StatusOK), nil)
+	}
+	var buf bytes.Buffer
+	Write(&buf)
+	if strings.Contains(buf.String(), "attacker.test") {
+		t.Errorf("unregistered host used as a label:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `tempest_request_duration_seconds_count{host="other"}`) {
+		t.Errorf("missing series for unregistered hosts:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `tempest_request_duration_seconds_bucket{host="errors.example",le="+Inf"}`) {
+		t.Errorf("missing series for registered host:\n%s", buf.String())
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&net.DNSError{Err: "no such host", Name: "x.test"}, "dns"},
+		{context.DeadlineExceeded, "timeout"},
+		{fmt.Errorf("dial: %w", syscall.ECONNREFUSED), "refused"},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), "reset"},
+		{fmt.Errorf("something else"), "other"},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{200, ""}, {301, ""}, {404, "status_404"}, {429, "status_429"}, {403, "status_4xx"}, {500, "status_5xx"}, {503, "status_5xx"},
+	}
+	for _, tt := range tests {
+		if got := ClassifyStatus(tt.code); got != tt.want {
+			t.Errorf("ClassifyStatus(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestExposition(t *testing.T) {
+	v := newVec("test_total", "Test counter.", "counter", "name", "kind")
+	v.Inc("a\"b", "x\\y\nz")
+	v.Add(2.5, "plain", "")
+	var buf bytes.Buffer
+	v.write(&buf)
+	want := "# HELP test_total Test counter.\n# TYPE test_total counter\n" +
+		`test_total{name="a\"b",kind="x\\y\nz"} 1` + "\n" +
+		`test_total{name="plain",kind=""} 2.5` + "\n"
+	if buf.String() != want {
+		t.Errorf("write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	h := &Histogram{name: "test_seconds", help: "Test histogram.", labels: []string{"host"}, buckets: []float64{0.1, 1}, series: make(map[string]*series)}
+	h.Observe(0.05, "h")
+	h.Observe(0.5, "h")
+	h.Observe(5, "h")
+	buf.Reset()
+	h.write(&buf)
+	for _, line := range []string{
+		`test_seconds_bucket{host="h",le="0.1"} 1`,
+		`test_seconds_bucket{host="h",le="1"} 2`,
+		`test_seconds_bucket{host="h",le="+Inf"} 3`,
+		`test_seconds_sum{host="h"} 5.55`,
+		`test_seconds_count{host="h"} 3`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("histogram output missing %q:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package normalize recovers links that were defanged or obfuscated in order to dodge scrapers, so that they can be
+// picked up by the providers' Extract functions.
+package normalize
+
+import (
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Techniques recorded in Entry.Obfuscation
+const (
+	Invisible  = "invisible"  // Zero-width/invisible Unicode characters
+	Defanged   = "defanged"   // hxxp://, [.], (dot) and friends
+	Escaped    = "escaped"    // Markdown backslash escaping and HTML entities
+	Split      = "split"      // Links split over multiple lines
+	Base64     = "base64"     // Base64 encoded links
+	URLEncoded = "urlencoded" // Percent-encoded links
+)
+
+// Compile RegEx expressions for recovery of links
+var scheme *regexp.Regexp = regexp.MustCompile(`(?i)\b(hxxps?|h\*\*ps?|h__ps?)(\[?:\]?//|\[://\])`)                      // Defanged schemes
+var dot *regexp.Regexp = regexp.MustCompile(`(?i)\s?(\[\.\]|\(\.\)|\{\.\}|\[dot\]|\(dot\)|\{dot\})\s?`)                  // Defanged dots
+var slash *regexp.Regexp = regexp.MustCompile(`(?i)(\[/\]|\(/\)|\[slash\]|\(slash\))`)                                   // Defanged slashes
+var colon *regexp.Regexp = regexp.MustCompile(`(https?)\[:\]//`)                                                         // Defanged colons
+var escape *regexp.Regexp = regexp.MustCompile(`\\([\\.\-_#*+!()\[\]{}/:~=?&%])`)                                        // Markdown backslash escapes
+var entity *regexp.Regexp = regexp.MustCompile(`&#(x2[eEfF]|x3[aA]|46|47|58);`)                                          // HTML entities for '.', '/' and ':'
+var tail *regexp.Regexp = regexp.MustCompile(`(?i)(https?://|magnet:\?)[^\s"'<>]*$`)                                     // Link at the end of a line
+var head *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9._~%/#?=&+!-]+$`)                                               // Continuation at the start of the next line
+var fragment *regexp.Regexp = regexp.MustCompile(`[/#?=&]|[A-Za-z].*[0-9]|[0-9].*[A-Za-z]`)                              // Evidence of a URL fragment (delimiter, or mixed letters and digits)
+var b64 *regexp.Regexp = regexp.MustCompile(`[A-Za-z0-9+/_-]{16,}={0,2}`)                                                // Base64 blocks
+var pct *regexp.Regexp = regexp.MustCompile(`(?i)(https?|magnet)(%3A%2F%2F|%3A%3F|://)[^\s"'<>]*%[0-9a-f]{2}[^\s"'<>]*`) // Percent-encoded links
+var link *regexp.Regexp = regexp.MustCompile(`(?i)(https?://|magnet:\?)`)                                                // Evidence of a link within decoded text
+
+// invisible contains the zero-width/invisible characters stripped from the contents
+var invisible = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\u2060", "", "\ufeff", "", "\u00ad", "", "\u180e", "", "\u2061", "", "\u2062", "", "\u2063", "", "\u2064", "")
+
+// MaxDepth is the maximum number of times decoded text is decoded again (e.g. base64 within base64)
+var MaxDepth = 2
+
+// Recovery represents text recovered from the contents of a paste and the technique(s) that hid it
+type Recovery struct {
+	Technique string // Comma separated list of the techniques undone to recover the text
+	Text      string // The recovered text, one recovered line/token per line
+}
+
+// Recover takes the contents of a paste and returns the text recovered by undoing common defanging and obfuscation
+// techniques, grouped by technique. Only the lines/tokens that were changed are returned, so the contents that were
+// already readable are not delegated twice.
+func Recover(contents string) []Recovery {
+	groups := make(map[string][]string)
+	var order []string
+	add := func(technique, text string) {
+		if _, ok := groups[technique]; !ok {
+			order = append(order, technique)
+		}
+		groups[technique] = append(groups[technique], text)
+	}
+
+	recover(contents, nil, 0, add)
+
+	var recovered []Recovery
+	for _, t := range order {
+		recovered = append(recovered, Recovery{Technique: t, Text: strings.Join(groups[t], "\n")})
+	}
+	return recovered
+}
+
+// recover undoes the line based techniques and decodes any encoded blocks, passing recovered text to add. The
+// techniques undone to reach the contents so far are passed via used.
+func recover(contents string, used []string, depth int, add func(string, string)) {
+	lines := strings.Split(strings.ReplaceAll(contents, "\r\n", "\n"), "\n")
+	clean := make([]string, len(lines))
+	for i, l := range lines {
+		c, t := Line(l)
+		clean[i] = c
+		if len(t) > 0 || len(used) > 0 {
+			add(technique(append(t, used...)), c)
+		}
+	}
+
+	for _, j := range Join(clean) {
+		add(technique(append([]string{Split}, used...)), j)
+	}
+
+	if depth >= MaxDepth {
+		return
+	}
+	full := strings.Join(clean, "\n")
+	for _, d := range DecodeBase64(full) {
+		recover(d, append([]string{Base64}, used...), depth+1, add)
+	}
+	for _, d := range DecodeURL(full) {
+		recover(d, append([]string{URLEncoded}, used...), depth+1, add)
+	}
+}
+
+// technique joins a list of techniques into the value stored in Entry.Obfuscation, removing duplicates
+func technique(t []string) string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, v := range t {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// Line undoes invisible characters, defanging and escaping on a single line, returning the cleaned line alongside the
+// techniques that were undone (if any).
+func Line(line string) (string, []string) {
+	var used []string
+	if s := invisible.Replace(line); s != line {
+		line = s
+		used = append(used, Invisible)
+	}
+	if s := Refang(line); s != line {
+		line = s
+		used = append(used, Defanged)
+	}
+	if s := Unescape(line); s != line {
+		line = s
+		used = append(used, Escaped)
+	}
+	return line, used
+}
+
+// Refang undoes common defanging, e.g. hxxps://mega[.]nz becomes https://mega.nz
+func Refang(s string) string {
+	s = scheme.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.ContainsAny(scheme.FindStringSubmatch(m)[1], "sS") {
+			return "https://"
+		}
+		return "http://"
+	})
+	s = colon.ReplaceAllString(s, "$1://")
+	s = dot.ReplaceAllString(s, ".")
+	return slash.ReplaceAllString(s, "/")
+}
+
+// Unescape removes markdown backslash escaping (e.g. mega\.nz) and decodes HTML entities commonly used to hide dots,
+// slashes and colons
+func Unescape(s string) string {
+	s = escape.ReplaceAllString(s, "$1")
+	return entity.ReplaceAllStringFunc(s, func(m string) string {
+		switch strings.ToLower(m) {
+		case "&#x2e;", "&#46;":
+			return "."
+		case "&#x2f;", "&#47;":
+			return "/"
+		default:
+			return ":"
+		}
+	})
+}
+
+// Join returns links that were split over multiple lines, e.g. a link ending one line and continuing on the next. The
+// continuation must be the only token on its line and look like a URL fragment: it may only contain URL characters,
+// and must contain a URL delimiter (/, #, ?, = or &) or mix letters and digits (e.g. the rest of an ID or key). This
+// keeps a link at the end of a line from being glued to an ordinary word on the next line.
+func Join(lines []string) []string {
+	var joined []string
+	for i := 0; i < len(lines)-1; i++ {
+		start := tail.FindString(strings.TrimSpace(lines[i]))
+		if start == "" {
+			continue
+		}
+		next := strings.TrimSpace(lines[i+1])
+		// Encoded links are recovered by DecodeBase64 instead
+		if next == "" || !head.MatchString(next) || !fragment.MatchString(next) || link.MatchString(next) || len(DecodeBase64(next)) > 0 {
+			continue
+		}
+		joined = append(joined, start+next)
+	}
+	return joined
+}
+
+// DecodeBase64 returns the decoded contents of every base64 block (standard or URL alphabet, padded or not) within a
+// string that decodes to printable text containing a (possibly defanged) link, or containing another such block
+// (nested encoding)
+func DecodeBase64(s string) []string {
+	var decoded []string
+	for _, m := range b64.FindAllString(s, -1) {
+		raw := strings.TrimRight(m, "=")
+		for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding} {
+			d, err := enc.DecodeString(raw)
+			if err != nil {
+				continue
+			}
+			if printable(d) && (link.MatchString(Refang(string(d))) || len(DecodeBase64(string(d))) > 0) {
+				decoded = append(decoded, string(d))
+				break
+			}
+		}
+	}
+	return decoded
+}
+
+// DecodeURL returns the decoded form of every percent-encoded link within a string
+func DecodeURL(s string) []string {
+	var decoded []string
+	for _, m := range pct.FindAllString(s, -1) {
+		d, err := url.QueryUnescape(m)
+		if err != nil || d == m {
+			continue
+		}
+		decoded = append(decoded, d)
+	}
+	return decoded
+}
+
+// printable reports whether a slice of bytes is valid UTF-8 text without control characters (other than whitespace)
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package normalize
+
+import (
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		techs []string
+	}{
+		{"https://mega.nz/file/abc", "https://mega.nz/file/abc", nil},
+		{"hxxps://mega[.]nz/file/abc", "https://mega.nz/file/abc", []string{Defanged}},
+		{"hxxp[:]//gofile(dot)io/d/abcdef", "http://gofile.io/d/abcdef", []string{Defanged}},
+		{"https[:]//mega{.}nz[/]file", "https://mega.nz/file", []string{Defanged}},
+		{"https://mega\\.nz/file/abc\\#key", "https://mega.nz/file/abc#key", []string{Escaped}},
+		{"https&#58;&#x2f;&#x2F;mega&#46;nz", "https://mega.nz", []string{Escaped}},
+		{"https://me\u200bga.nz/fi\ufeffle", "https://mega.nz/file", []string{Invisible}},
+		{"hxxps://me\u200dga[.]nz\\/file", "https://mega.nz/file", []string{Invisible, Defanged, Escaped}},
+	}
+	for _, tt := range tests {
+		got, techs := Line(tt.in)
+		if got != tt.want || !slices.Equal(techs, tt.techs) {
+			t.Errorf("Line(%q) = %q, %v, want %q, %v", tt.in, got, techs, tt.want, tt.techs)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{"split key", []string{"https://mega.nz/folder/abcd1234#", "Xyz_9-abc"}, []string{"https://mega.nz/folder/abcd1234#Xyz_9-abc"}},
+		{"split path", []string{"see https://drive.google.com/file/d/", "1AbCdEf/view"}, []string{"https://drive.google.com/file/d/1AbCdEf/view"}},
+		{"ordinary word", []string{"https://drive.google.com/file/d/1AbCdEf", "Enjoy!"}, nil},
+		{"ordinary words", []string{"https://www.dropbox.com/s/abc/file.zip", "password"}, nil},
+		{"sentence", []string{"https://gofile.io/d/abcdef", "more links below"}, nil},
+		{"next link", []string{"https://gofile.io/d/abcdef", "https://gofile.io/d/ghijkl"}, nil},
+		{"html", []string{"https://gofile.io/d/", "<br>"}, nil},
+		{"empty", []string{"https://gofile.io/d/abcdef", ""}, nil},
+		{"no link", []string{"hello", "abc123"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Join(tt.lines); !slices.Equal(got, tt.want) {
+				t.Errorf("Join(%q) = %q, want %q", tt.lines, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBase64(t *testing.T) {
+	link := "https://mega.nz/folder/abcd1234#key"
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		text := "download: " + enc.EncodeToString([]byte(link)) + " enjoy"
+		if got := DecodeBase64(text); len(got) != 1 || got[0] != link {
+			t.Errorf("DecodeBase64(%q) = %q, want [%q]", text, got, link)
+		}
+	}
+	// Blocks decoding to binary data or text without links are ignored
+	for _, text := range []string{base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}), base64.StdEncoding.EncodeToString([]byte("just some ordinary text")), "AAAAAAAAAAAAAAAAAAAAAAAA"} {
+		if got := DecodeBase64(text); len(got) != 0 {
+			t.Errorf("DecodeBase64(%q) = %q, want none", text, got)
+		}
+	}
+}
+
+func TestDecodeURL(t *testing.T) {
+	link := "https://gofile.io/d/abcdef"
+	if got := DecodeURL("x " + url.QueryEscape(link) + " y"); len(got) != 1 || got[0] != link {
+		t.Errorf("DecodeURL() = %q, want [%q]", got, link)
+	}
+	if got := DecodeURL("https://gofile.io/d/abc%zz"); len(got) != 0 {
+		t.Errorf("DecodeURL(invalid escape) = %q, want none", got)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	nested := base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString([]byte("hxxps://gofile[.]io/d/abcdef"))))
+	contents := strings.Join([]string{
+		"https://mega.nz/file/plain",
+		"hxxps://mega[.]nz/file/defanged",
+		nested,
+		"https://mega.nz/folder/abcd1234#",
+		"Key123",
+	}, "\r\n")
+
+	got := make(map[string]string)
+	for _, r := range Recover(contents) {
+		got[r.Technique] = r.Text
+	}
+	if strings.Contains(strings.Join(mapValues(got), "\n"), "https://mega.nz/file/plain") {
+		t.Errorf("Recover() returned a line that was not obfuscated: %v", got)
+	}
+	if !strings.Contains(got[Defanged], "https://mega.nz/file/defanged") {
+		t.Errorf("Recover()[%s] = %q", Defanged, got[Defanged])
+	}
+	if !strings.Contains(got["defanged,base64"], "https://gofile.io/d/abcdef") {
+		t.Errorf("Recover() did not recover the nested base64 link: %v", got)
+	}
+	if got[Split] != "https://mega.nz/folder/abcd1234#Key123" {
+		t.Errorf("Recover()[%s] = %q", Split, got[Split])
+	}
+}
+
+func TestRecoverDepth(t *testing.T) {
+	// Decoding stops after MaxDepth levels
+	s := "https://gofile.io/d/abcdef"
+	for i := 0; i <= MaxDepth+1; i++ {
+		s = base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	for _, r := range Recover(s) {
+		if strings.Contains(r.Text, "gofile.io") {
+			t.Errorf("Recover() decoded beyond MaxDepth: %+v", r)
+		}
+	}
+}
+
+func mapValues(m map[string]string) []string {
+	var out []string
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return out
+}
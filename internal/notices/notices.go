@@ -0,0 +1,249 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package notices contains functions used to generate takedown (e.g. DMCA/abuse) notices from entries, one per
+// hosting service, using per-service templates and abuse contacts read from a config directory.
+package notices
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+//go:embed default.tmpl
+var defaultTemplate string
+
+// ConfigFile is the name of the config file within the config directory
+const ConfigFile = "config.json"
+
+// slugRe matches characters that are replaced when converting a service name into a file name
+var slugRe *regexp.Regexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Sender represents the person/organization sending the notices
+type Sender struct {
+	Name         string `json:"name"`
+	Organization string `json:"organization"`
+	Email        string `json:"email"`
+	Address      string `json:"address"`
+	Phone        string `json:"phone"`
+}
+
+// Contact represents the abuse contact of a service
+type Contact struct {
+	To      string `json:"to"`      // Abuse contact address(es)
+	Subject string `json:"subject"` // Optional subject, overriding the template's default
+}
+
+// Config represents the contents of the config file
+type Config struct {
+	Sender   Sender             `json:"sender"`
+	Contacts map[string]Contact `json:"contacts"` // Keyed by Entry.Service
+	dir      string
+}
+
+// Link represents a single link within a notice
+type Link struct {
+	Link      string
+	Title     string
+	Size      string
+	Type      string
+	FirstSeen string
+	Sources   []string
+}
+
+// Notice represents the data passed to a service's template
+type Notice struct {
+	Service string
+	Contact string
+	Subject string
+	Date    string
+	Sender  Sender
+	Count   int
+	Links   []Link
+}
+
+// Slug converts a service name into the name used for its template and notice files, e.g. Google Drive becomes
+// google-drive
+func Slug(service string) string {
+	return strings.Trim(slugRe.ReplaceAllString(strings.ToLower(service), "-"), "-")
+}
+
+// LoadConfig reads the config file within a config directory
+func LoadConfig(dir string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{dir: dir}
+	if err = json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Init writes an example config file and the default template to a config directory, without overwriting existing
+// files. A contact is added for every service specified.
+func Init(dir string, services []string) error {
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0700); err != nil {
+		return err
+	}
+	cfg := Config{Sender: Sender{Name: "Your Name", Organization: "Your Organization", Email: "you@example.com"}, Contacts: make(map[string]Contact)}
+	for _, s := range services {
+		cfg.Contacts[s] = Contact{}
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		filepath.Join(dir, ConfigFile):                  append(data, '\n'),
+		filepath.Join(dir, "templates", "default.tmpl"): []byte(defaultTemplate),
+	}
+	for name, contents := range files {
+		if _, err := os.Stat(name); err == nil {
+			continue
+		}
+		if err := os.WriteFile(name, contents, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Template returns the template of a service: templates/<slug>.tmpl within the config directory, falling back to
+// templates/default.tmpl and then to the built-in default template
+func (c *Config) Template(service string) (*template.Template, error) {
+	text := defaultTemplate
+	for _, name := range []string{Slug(service) + ".tmpl", "default.tmpl"} {
+		data, err := os.ReadFile(filepath.Join(c.dir, "templates", name))
+		if err == nil {
+			text = string(data)
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return template.New(service).Funcs(template.FuncMap{
+		"add":  func(a, b int) int { return a + b },
+		"join": strings.Join,
+	}).Parse(text)
+}
+
+// Group groups entries by service, merging repeat links. The first seen date of a link is the earliest Seen value of
+// its entries. Services and links are sorted by name.
+func Group(entries []models.Entry) map[string][]Link {
+	type merged struct {
+		link    Link
+		sources map[string]bool
+	}
+	byService := make(map[string]map[string]*merged)
+	for _, v := range entries {
+		service := v.Service
+		if service == "" {
+			service = "Unknown"
+		}
+		if byService[service] == nil {
+			byService[service] = make(map[string]*merged)
+		}
+		m, ok := byService[service][v.Link]
+		if !ok {
+			m = &merged{link: Link{Link: v.Link}, sources: make(map[string]bool)}
+			byService[service][v.Link] = m
+		}
+		if m.link.Title == "" {
+			m.link.Title = v.Title
+		}
+		if m.link.Size == "" {
+			m.link.Size = v.Size
+		}
+		if m.link.Type == "" {
+			m.link.Type = v.Type
+		}
+		if v.Seen != "" && (m.link.FirstSeen == "" || v.Seen < m.link.FirstSeen) {
+			m.link.FirstSeen = v.Seen
+		}
+		if v.Source != "" {
+			m.sources[v.Source] = true
+		}
+	}
+
+	grouped := make(map[string][]Link)
+	for service, links := range byService {
+		for _, m := range links {
+			for s := range m.sources {
+				m.link.Sources = append(m.link.Sources, s)
+			}
+			sort.Strings(m.link.Sources)
+			grouped[service] = append(grouped[service], m.link)
+		}
+		sort.Slice(grouped[service], func(i, j int) bool { return grouped[service][i].Link < grouped[service][j].Link })
+	}
+	return grouped
+}
+
+// Render fills the template of a service with its links
+func (c *Config) Render(service string, links []Link) (string, error) {
+	tmpl, err := c.Template(service)
+	if err != nil {
+		return "", err
+	}
+	contact := c.Contacts[service]
+	n := Notice{Service: service, Contact: contact.To, Subject: contact.Subject, Date: time.Now().Format("January 2, 2006"), Sender: c.Sender, Count: len(links), Links: links}
+	var b strings.Builder
+	if err = tmpl.Execute(&b, n); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Write renders a notice for every service and writes each to <slug>.txt within the output directory, returning the
+// paths of the written files
+func (c *Config) Write(out string, grouped map[string][]Link) ([]string, error) {
+	if err := os.MkdirAll(out, 0700); err != nil {
+		return nil, err
+	}
+	var services []string
+	for s := range grouped {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+
+	var written []string
+	for _, s := range services {
+		text, err := c.Render(s, grouped[s])
+		if err != nil {
+			return written, err
+		}
+		path := filepath.Join(out, Slug(s)+".txt")
+		if err = os.WriteFile(path, []byte(text), 0600); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
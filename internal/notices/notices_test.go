@@ -0,0 +1,106 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package notices
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct{ service, want string }{
+		{"Google Drive", "google-drive"},
+		{"MediaFire", "mediafire"},
+		{"../../etc/passwd", "etc-passwd"},
+		{"  Yandex.Disk  ", "yandex-disk"},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.service); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.service, got, tt.want)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	entries := []models.Entry{
+		{Link: "https://mega.nz/b", Service: "Mega", Seen: "2023-02-01T00:00:00Z", Source: "https://rentry.co/b"},
+		{Link: "https://mega.nz/a", Service: "Mega", Title: "a", Seen: "2023-03-01T00:00:00Z", Source: "https://rentry.co/a"},
+		{Link: "https://mega.nz/b", Service: "Mega", Title: "b", Size: "1 GB", Seen: "2023-01-01T00:00:00Z", Source: "https://rentry.co/a"},
+		{Link: "https://mega.nz/b", Service: "Mega", Source: "https://rentry.co/b"},
+		{Link: "https://example.com/x"},
+	}
+	want := map[string][]Link{
+		"Mega": {
+			{Link: "https://mega.nz/a", Title: "a", FirstSeen: "2023-03-01T00:00:00Z", Sources: []string{"https://rentry.co/a"}},
+			{Link: "https://mega.nz/b", Title: "b", Size: "1 GB", FirstSeen: "2023-01-01T00:00:00Z", Sources: []string{"https://rentry.co/a", "https://rentry.co/b"}},
+		},
+		"Unknown": {{Link: "https://example.com/x"}},
+	}
+	if got := Group(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("Group() = %+v, want %+v", got, want)
+	}
+	if got := Group(nil); len(got) != 0 {
+		t.Errorf("Group(nil) = %+v, want no services", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	if err := Init(dir, []string{"Mega", "Google Drive"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "google-drive.tmpl"), []byte("Drive: {{.Count}} {{range .Links}}{{.Link}} {{end}}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Contacts["Mega"] = Contact{To: "abuse@mega.test"}
+
+	out := filepath.Join(t.TempDir(), "out")
+	grouped := map[string][]Link{
+		"Mega":             {{Link: "https://mega.nz/a", Title: "{{.Sender.Email}}"}, {Link: "https://mega.nz/b"}},
+		"Google Drive":     {{Link: "https://drive.google.com/x"}},
+		"../../etc/passwd": {{Link: "https://example.com"}},
+	}
+	written, err := cfg.Write(out, grouped)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	wantFiles := []string{filepath.Join(out, "etc-passwd.txt"), filepath.Join(out, "google-drive.txt"), filepath.Join(out, "mega.txt")}
+	if !reflect.DeepEqual(written, wantFiles) {
+		t.Errorf("Write() = %q, want %q", written, wantFiles)
+	}
+
+	mega, _ := os.ReadFile(filepath.Join(out, "mega.txt"))
+	for _, want := range []string{"To: abuse@mega.test", "2 Mega links", "1. https://mega.nz/a", "Title: {{.Sender.Email}}", "2. https://mega.nz/b", "Title: unknown"} {
+		if !strings.Contains(string(mega), want) {
+			t.Errorf("mega.txt does not contain %q:\n%s", want, mega)
+		}
+	}
+	drive, _ := os.ReadFile(filepath.Join(out, "google-drive.txt"))
+	if string(drive) != "Drive: 1 https://drive.google.com/x " {
+		t.Errorf("google-drive.txt = %q, want the service template", drive)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package providers contains the registry of cloud storage/file sharing modules that Tempest delegates to
+package providers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/pkg/bunkr"
+	"github.com/ax-i-om/tempest/pkg/catbox"
+	"github.com/ax-i-om/tempest/pkg/cloudmailru"
+	"github.com/ax-i-om/tempest/pkg/cyberdrop"
+	"github.com/ax-i-om/tempest/pkg/discord"
+	"github.com/ax-i-om/tempest/pkg/dood"
+	"github.com/ax-i-om/tempest/pkg/dropbox"
+	"github.com/ax-i-om/tempest/pkg/gofile"
+	"github.com/ax-i-om/tempest/pkg/googledrive"
+	"github.com/ax-i-om/tempest/pkg/krakenfiles"
+	"github.com/ax-i-om/tempest/pkg/mediafire"
+	"github.com/ax-i-om/tempest/pkg/mega"
+	"github.com/ax-i-om/tempest/pkg/models"
+	"github.com/ax-i-om/tempest/pkg/onedrive"
+	"github.com/ax-i-om/tempest/pkg/pixeldrain"
+	"github.com/ax-i-om/tempest/pkg/sendvid"
+	"github.com/ax-i-om/tempest/pkg/streamtape"
+	"github.com/ax-i-om/tempest/pkg/telegram"
+	"github.com/ax-i-om/tempest/pkg/terabox"
+	"github.com/ax-i-om/tempest/pkg/torrent"
+	"github.com/ax-i-om/tempest/pkg/yandexdisk"
+)
+
+// Provider represents a cloud storage/file sharing module
+type Provider struct {
+	Name     string                                       // Name of the module, used in logs
+	Hosts    []string                                     // Domains handled by the module (subdomains are matched too)
+	Extract  func(string) ([]string, error)               // The module's Extract function
+	Delegate func(string, string) ([]models.Entry, error) // The module's Delegate function
+}
+
+// Providers contains every registered module, in the order they are delegated to
+var Providers = []Provider{
+	{Name: "Mega", Hosts: []string{"mega.nz", "mega.co.nz"}, Extract: mega.Extract, Delegate: mega.Delegate},
+	{Name: "Gofile", Hosts: []string{"gofile.io"}, Extract: gofile.Extract, Delegate: gofile.Delegate},
+	{Name: "Sendvid", Hosts: []string{"sendvid.com"}, Extract: sendvid.Extract, Delegate: sendvid.Delegate},
+	{Name: "Cyberdrop", Hosts: []string{"cyberdrop.me"}, Extract: cyberdrop.Extract, Delegate: cyberdrop.Delegate},
+	{Name: "Bunkr", Hosts: []string{"bunkr.is", "bunkr.ru", "bunkr.su", "bunkrr.su", "bunkr.la", "bunkr.sk", "bunkr.si"}, Extract: bunkr.Extract, Delegate: bunkr.Delegate},
+	{Name: "Google Drive", Hosts: []string{"drive.google.com", "docs.google.com"}, Extract: googledrive.Extract, Delegate: googledrive.Delegate},
+	{Name: "Dood", Hosts: []string{"dood.la", "dood.re", "dood.wf", "dood.so", "dood.yt", "dood.pm", "dood.sh", "dood.to", "dood.ws", "dood.one", "dood.watch", "dood.pro", "dood.stream", "doods.pro"}, Extract: dood.Extract, Delegate: dood.Delegate},
+	{Name: "CloudMailRu", Hosts: []string{"cloud.mail.ru"}, Extract: cloudmailru.Extract, Delegate: cloudmailru.Delegate},
+	{Name: "Pixeldrain", Hosts: []string{"pixeldrain.com"}, Extract: pixeldrain.Extract, Delegate: pixeldrain.Delegate},
+	{Name: "MediaFire", Hosts: []string{"mediafire.com"}, Extract: mediafire.Extract, Delegate: mediafire.Delegate},
+	{Name: "Yandex Disk", Hosts: []string{"disk.yandex.ru", "disk.yandex.com", "disk.yandex.com.tr", "disk.yandex.kz", "disk.yandex.by", "disk.yandex.ua", "yadi.sk"}, Extract: yandexdisk.Extract, Delegate: yandexdisk.Delegate},
+	{Name: "Terabox", Hosts: []string{"terabox.com", "terabox.app", "teraboxapp.com", "1024terabox.com", "4funbox.com", "mirrobox.com", "nephobox.com"}, Extract: terabox.Extract, Delegate: terabox.Delegate},
+	{Name: "Dropbox", Hosts: []string{"dropbox.com"}, Extract: dropbox.Extract, Delegate: dropbox.Delegate},
+	{Name: "OneDrive", Hosts: []string{"1drv.ms", "onedrive.live.com"}, Extract: onedrive.Extract, Delegate: onedrive.Delegate},
+	{Name: "Catbox", Hosts: []string{"catbox.moe"}, Extract: catbox.Extract, Delegate: catbox.Delegate},
+	{Name: "Streamtape", Hosts: []string{"streamtape.com", "streamtape.to", "streamtape.net", "streamtape.xyz", "streamtape.site", "streamtape.cc", "strtape.cloud", "strtape.tech", "streamta.pe", "strcloud.link", "tapecontent.net"}, Extract: streamtape.Extract, Delegate: streamtape.Delegate},
+	{Name: "KrakenFiles", Hosts: []string{"krakenfiles.com"}, Extract: krakenfiles.Extract, Delegate: krakenfiles.Delegate},
+	{Name: "BitTorrent", Extract: torrent.ExtractLinks, Delegate: torrent.Delegate},
+	{Name: "Telegram", Hosts: []string{"t.me", "telegram.me", "telegram.dog"}, Extract: telegram.Extract, Delegate: telegram.Delegate},
+	{Name: "Discord", Hosts: []string{"discord.gg", "discord.com", "discordapp.com"}, Extract: discord.Extract, Delegate: discord.Delegate},
+}
+
+// Register the hosts handled by the providers as request duration labels
+func init() {
+	for _, p := range Providers {
+		metrics.RegisterHosts(p.Hosts...)
+	}
+}
+
+// Handles reports whether a host is handled by a registered provider. The host is compared case-insensitively, and
+// subdomains of a provider's hosts (e.g. www.mediafire.com) are handled too.
+func Handles(host string) bool {
+	return Match(host) != nil
+}
+
+// Match returns the registered provider that handles a host, or nil if there is none
+func Match(host string) *Provider {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for i, p := range Providers {
+		for _, h := range p.Hosts {
+			if host == h || strings.HasSuffix(host, "."+h) {
+				return &Providers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Find returns the registered provider that handles a link, or nil if there is none. Magnet links, and .torrent file
+// links whose host is not handled by another provider, are handled by the BitTorrent module.
+func Find(link string) *Provider {
+	if strings.HasPrefix(strings.ToLower(link), "magnet:") {
+		return byName("BitTorrent")
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil
+	}
+	if p := Match(u.Hostname()); p != nil {
+		return p
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && strings.HasSuffix(strings.ToLower(u.Path), ".torrent") {
+		return byName("BitTorrent")
+	}
+	return nil
+}
+
+// byName returns the registered provider with the specified name, or nil if there is none
+func byName(name string) *Provider {
+	for i, p := range Providers {
+		if p.Name == name {
+			return &Providers[i]
+		}
+	}
+	return nil
+}
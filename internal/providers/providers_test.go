@@ -0,0 +1,55 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package providers
+
+import "testing"
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a", "BitTorrent"},
+		{"https://example.com/files/ubuntu.iso.torrent", "BitTorrent"},
+		{"http://example.com/a.TORRENT?dl=1", "BitTorrent"},
+		{"https://mega.nz/file/abc#key", "Mega"},
+		{"https://www.mediafire.com/file/abc/test.zip/file", "MediaFire"},
+		{"https://example.com/page", ""},
+		{"ftp://example.com/a.torrent", ""},
+		{"://bad", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if p := Find(tt.link); p != nil {
+			got = p.Name
+		}
+		if got != tt.want {
+			t.Errorf("Find(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestTorrentExtract(t *testing.T) {
+	p := Find("https://example.com/a.torrent")
+	if p == nil {
+		t.Fatal("no provider for .torrent links")
+	}
+	links, err := p.Extract("see https://example.com/a.torrent and magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a")
+	if err != nil || len(links) != 2 {
+		t.Errorf("Extract() = %v, %v, want a magnet link and a .torrent link", links, err)
+	}
+}
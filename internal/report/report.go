@@ -0,0 +1,213 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package report contains functions used to render result files generated by Tempest as a self-contained HTML
+// report, grouped by service and source paste.
+package report
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+// MaxThumbnailSize is the maximum size of a thumbnail embedded into the report, larger thumbnails are linked instead
+var MaxThumbnailSize int64 = 1 << 20
+
+// Row represents a single entry within the report, alongside the values used for sorting/filtering
+type Row struct {
+	models.Entry
+	Bytes     int64        // Size in bytes, -1 if unknown
+	Date      string       // Upload date, or the date the entry was seen if unknown
+	Timestamp int64        // Date as a unix timestamp, 0 if unknown
+	Thumb     template.URL // Thumbnail URL or data URI
+	Inline    bool         // Whether Thumb is an embedded data URI (shown as an image), other thumbnails are linked
+	Href      template.URL // Link, if it uses one of LinkSchemes
+}
+
+// LinkSchemes are the URL schemes of entry links rendered as hyperlinks, other links are rendered as text
+var LinkSchemes = []string{"http", "https", "magnet"}
+
+// Source represents the entries of a service found within a single source paste
+type Source struct {
+	Source string
+	Rows   []Row
+}
+
+// Service represents the entries of a single service
+type Service struct {
+	Name    string
+	Count   int
+	Sources []Source
+}
+
+// Report represents the data passed to the HTML template
+type Report struct {
+	Title     string
+	Generated string
+	Total     int
+	Services  []Service
+	Types     []string
+}
+
+// dateFormats contains the date formats used by providers, tried in order when parsing dates
+var dateFormats = []string{time.RFC3339, "Jan 02, 2006", "2006-01-02 15:04:05", "2006-01-02", "02.01.2006", "January 2, 2006"}
+
+// ParseDate converts a date written by a provider into a unix timestamp, returning 0 if the date cannot be parsed
+func ParseDate(date string) int64 {
+	for _, f := range dateFormats {
+		if t, err := time.Parse(f, strings.TrimSpace(date)); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
+
+// Build groups entries by service and source paste. Services are sorted by entry count, sources by name.
+func Build(title string, entries []models.Entry) Report {
+	grouped := make(map[string]map[string][]Row)
+	types := make(map[string]bool)
+	for _, v := range entries {
+		service := v.Service
+		if service == "" {
+			service = "Unknown"
+		}
+		if grouped[service] == nil {
+			grouped[service] = make(map[string][]Row)
+		}
+		date := v.Uploaded
+		ts := ParseDate(date)
+		if ts == 0 {
+			date, ts = v.Seen, ParseDate(v.Seen)
+		}
+		if v.Type != "" {
+			types[v.Type] = true
+		}
+		grouped[service][v.Source] = append(grouped[service][v.Source], Row{Entry: v, Bytes: handlers.ParseBytes(v.Size), Date: date, Timestamp: ts, Thumb: template.URL(safeURL(v.Thumbnail, "http", "https")), Href: template.URL(safeURL(v.Link, LinkSchemes...))})
+	}
+
+	rep := Report{Title: title, Generated: time.Now().UTC().Format(time.RFC3339), Total: len(entries)}
+	for name, sources := range grouped {
+		s := Service{Name: name}
+		for source, rows := range sources {
+			s.Sources = append(s.Sources, Source{Source: source, Rows: rows})
+			s.Count += len(rows)
+		}
+		sort.Slice(s.Sources, func(i, j int) bool { return s.Sources[i].Source < s.Sources[j].Source })
+		rep.Services = append(rep.Services, s)
+	}
+	sort.Slice(rep.Services, func(i, j int) bool {
+		if rep.Services[i].Count != rep.Services[j].Count {
+			return rep.Services[i].Count > rep.Services[j].Count
+		}
+		return rep.Services[i].Name < rep.Services[j].Name
+	})
+	for t := range types {
+		rep.Types = append(rep.Types, t)
+	}
+	sort.Strings(rep.Types)
+	return rep
+}
+
+// safeURL returns a URL if it uses one of the allowed schemes, so that entries cannot inject javascript: URLs.
+// html/template would otherwise replace URLs with schemes it does not know (e.g. magnet:) with #ZgotmplZ.
+func safeURL(link string, schemes ...string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return link
+		}
+	}
+	return ""
+}
+
+// EmbedThumbnails downloads the thumbnails of a report and replaces their URLs with data URIs, so that the report
+// can be viewed fully offline. Thumbnails that fail to download, are not images or exceed MaxThumbnailSize are kept
+// as links.
+func EmbedThumbnails(rep *Report) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i := range rep.Services {
+		for j := range rep.Services[i].Sources {
+			for k := range rep.Services[i].Sources[j].Rows {
+				row := &rep.Services[i].Sources[j].Rows[k]
+				if row.Thumb == "" {
+					continue
+				}
+				wg.Add(1)
+				sem <- struct{}{}
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+					if data, err := fetchImage(string(row.Thumb)); err == nil {
+						row.Thumb, row.Inline = template.URL(data), true
+					} else {
+						handlers.LogErr(err, "failed to embed thumbnail "+string(row.Thumb))
+					}
+				}()
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// fetchImage downloads an image and returns it encoded as a data URI
+func fetchImage(link string) (string, error) {
+	res, err := handlers.GetRes(link)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	mimeType := strings.TrimSpace(strings.Split(res.Header.Get("Content-Type"), ";")[0])
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(mimeType, "image/") {
+		return "", os.ErrInvalid
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxThumbnailSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > MaxThumbnailSize {
+		return "", os.ErrInvalid
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(body), nil
+}
+
+// Render writes the report as a self-contained HTML page
+func Render(w io.Writer, rep Report) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{"bytes": handlers.FormatBytes}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, rep)
+}
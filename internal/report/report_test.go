@@ -0,0 +1,124 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package report
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestRenderLinks(t *testing.T) {
+	magnet := "magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=test"
+	entries := []models.Entry{
+		{Link: magnet, Service: "BitTorrent", Title: "torrent"},
+		{Link: "https://mega.nz/file/abc", Service: "Mega", Title: "<script>alert(1)</script>", Thumbnail: "javascript:alert(1)"},
+		{Link: "javascript:alert(1)", Service: "Mega"},
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, Build("test", entries)); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "ZgotmplZ") {
+		t.Errorf("Render() output contains ZgotmplZ")
+	}
+	if !strings.Contains(out, `href="magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&amp;dn=test"`) {
+		t.Errorf("Render() output does not link the magnet link")
+	}
+	if !strings.Contains(out, `href="https://mega.nz/file/abc"`) {
+		t.Errorf("Render() output does not link the https link")
+	}
+	if strings.Contains(out, `href="javascript:`) || strings.Contains(out, `src="javascript:`) {
+		t.Errorf("Render() output contains a javascript: URL")
+	}
+	if strings.Contains(out, "<script>alert(1)</script>") {
+		t.Errorf("Render() output contains an unescaped title")
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://a.com/x", "https://a.com/x"},
+		{"HTTP://a.com/x", "HTTP://a.com/x"},
+		{"magnet:?xt=urn:btih:abc", "magnet:?xt=urn:btih:abc"},
+		{"javascript:alert(1)", ""},
+		{"data:text/html,x", ""},
+		{"//a.com/x", ""},
+		{"%zz", ""},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.link, LinkSchemes...); got != tt.want {
+			t.Errorf("safeURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+	if got := safeURL("magnet:?xt=urn:btih:abc", "http", "https"); got != "" {
+		t.Errorf("safeURL(magnet, http, https) = %q, want empty", got)
+	}
+}
+
+func TestThumbnails(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/thumb.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	entries := []models.Entry{
+		{Link: "https://mega.nz/file/a", Service: "Mega", Thumbnail: srv.URL + "/thumb.png"},
+		{Link: "https://mega.nz/file/b", Service: "Mega", Thumbnail: srv.URL + "/missing.png"},
+	}
+
+	// Without embedding, no thumbnail is loaded by the page
+	var buf bytes.Buffer
+	if err := Render(&buf, Build("test", entries)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `<img src="`+srv.URL) {
+		t.Errorf("Render() hotlinks a thumbnail")
+	}
+	if !strings.Contains(buf.String(), `<a href="`+srv.URL+`/thumb.png"`) {
+		t.Errorf("Render() does not link the thumbnail")
+	}
+
+	// Embedded thumbnails are shown, those that failed to download stay links
+	rep := Build("test", entries)
+	EmbedThumbnails(&rep)
+	buf.Reset()
+	if err := Render(&buf, rep); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `<img src="data:image/png;base64,`) {
+		t.Errorf("Render() does not show the embedded thumbnail")
+	}
+	if strings.Contains(out, `<img src="`+srv.URL) || !strings.Contains(out, `<a href="`+srv.URL+`/missing.png"`) {
+		t.Errorf("Render() does not link the thumbnail that failed to download")
+	}
+}
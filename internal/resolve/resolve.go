@@ -0,0 +1,189 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package resolve contains functions used to resolve links hidden behind URL shorteners and link protectors, so that
+// the final links can be delegated to the providers.
+package resolve
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/metrics"
+)
+
+// Compile RegEx expressions for extraction of links
+var uLink *regexp.Regexp = regexp.MustCompile(`(https|http)://[^\s"'<>)\]]+`)
+
+// Shorteners contains the domains of known URL shorteners/link protectors (subdomains are matched too)
+var Shorteners = []string{
+	"bit.ly", "bitly.com", "j.mp", "tinyurl.com", "ouo.io", "ouo.press", "is.gd", "v.gd", "t.co", "goo.gl",
+	"cutt.ly", "rebrand.ly", "rb.gy", "shorturl.at", "tiny.cc", "ow.ly", "buff.ly", "s.id", "shorte.st", "sh.st",
+	"adf.ly", "bc.vc", "clk.sh", "exe.io", "shrinkme.io", "linkvertise.com", "link-to.net", "href.li", "rebrandly.com",
+	"t.ly", "lnkd.in", "tny.im", "short.gy", "shorturl.ac", "urlz.fr", "2no.co",
+}
+
+// Register the shorteners as request duration labels
+func init() {
+	metrics.RegisterHosts(Shorteners...)
+}
+
+// MaxRedirects is the maximum number of hops followed when resolving a link
+var MaxRedirects = 10
+
+// targetParams contains query parameters that link protectors commonly use to carry the destination link
+var targetParams = []string{"s", "url", "u", "link", "target", "dest", "destination", "r", "to", "href"}
+
+// client does not follow redirects, each hop is followed manually so the chain can be recorded
+var client = &http.Client{
+	Timeout: time.Second * 15, // connection timeout after 15 seconds
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// IsShortener reports whether a host belongs to a known URL shortener/link protector. The host is compared
+// case-insensitively, and subdomains are matched too.
+func IsShortener(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, s := range Shorteners {
+		if host == s || strings.HasSuffix(host, "."+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract returns a slice of all links to known URL shorteners/link protectors contained within a string, if any.
+func Extract(res string) ([]string, error) {
+	var links []string
+	seen := make(map[string]bool)
+	for _, link := range uLink.FindAllString(res, -1) {
+		u, err := url.Parse(link)
+		if err != nil || u.Path == "" || u.Path == "/" || !IsShortener(u.Hostname()) || seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	// Return all shortened links found within an http response
+	return links, nil
+}
+
+// ExtractTarget returns the destination link carried within the query of a shortener/link protector URL (e.g.
+// https://ouo.io/s/abc?s=https://mega.nz/... or https://href.li/?https://mega.nz/...), if any. Base64 encoded
+// destinations are decoded. Links to other hosts are ignored.
+func ExtractTarget(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || !IsShortener(u.Hostname()) {
+		return ""
+	}
+	if strings.HasPrefix(u.RawQuery, "http://") || strings.HasPrefix(u.RawQuery, "https://") {
+		return u.RawQuery
+	}
+	q := u.Query()
+	for _, p := range targetParams {
+		v := q.Get(p)
+		if v == "" {
+			continue
+		}
+		if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+			return v
+		}
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+			d, err := enc.DecodeString(v)
+			if err == nil && (strings.HasPrefix(string(d), "http://") || strings.HasPrefix(string(d), "https://")) {
+				return string(d)
+			}
+		}
+	}
+	return ""
+}
+
+// Next returns the next hop of a link, or an empty string if the link does not redirect. A HEAD request is used so
+// that no body is downloaded; shorteners that refuse HEAD requests are retried with a GET request whose body is
+// discarded unread.
+func Next(link string) (string, error) {
+	if target := ExtractTarget(link); target != "" {
+		return target, nil
+	}
+
+	res, err := do(http.MethodHead, link)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode == http.StatusMethodNotAllowed || res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusNotImplemented {
+		res, err = do(http.MethodGet, link)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if res.StatusCode < 300 || res.StatusCode > 399 {
+		return "", nil
+	}
+	loc, err := res.Location()
+	if err != nil {
+		if errors.Is(err, http.ErrNoLocation) {
+			return "", nil
+		}
+		return "", err
+	}
+	return loc.String(), nil
+}
+
+// do performs a request without following redirects and closes the body without reading it
+func do(method, link string) (*http.Response, error) {
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		handlers.LogErr(err, "failed to wrap new request")
+		return nil, err
+	}
+	start := time.Now()
+	res, err := client.Do(req)
+	metrics.ObserveRequest(req.URL.Hostname(), time.Since(start), res, err)
+	if err != nil {
+		return nil, err
+	}
+	return res, res.Body.Close()
+}
+
+// Resolve follows the redirects of a link, up to MaxRedirects hops, and returns the redirect chain. The first element
+// is the link itself and the last element is the final link. Hops are followed until a link no longer redirects.
+func Resolve(link string) ([]string, error) {
+	chain := []string{link}
+	seen := map[string]bool{link: true}
+	for i := 0; i < MaxRedirects; i++ {
+		next, err := Next(chain[len(chain)-1])
+		if err != nil {
+			return chain, err
+		}
+		// Stop when the link no longer redirects, or when a loop is detected
+		if next == "" || seen[next] {
+			break
+		}
+		seen[next] = true
+		chain = append(chain, next)
+	}
+	return chain, nil
+}
@@ -0,0 +1,139 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package resolve
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIsShortener(t *testing.T) {
+	tests := map[string]bool{
+		"bit.ly":          true,
+		"BIT.LY.":         true,
+		"www.tinyurl.com": true,
+		"notbit.ly":       false,
+		"example.com":     false,
+	}
+	for host, want := range tests {
+		if got := IsShortener(host); got != want {
+			t.Errorf("IsShortener(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	in := `https://bit.ly/abc https://bit.ly/ https://example.com/abc https://bit.ly/abc <a href="https://ouo.io/s/xyz?s=1">x</a>`
+	want := []string{"https://bit.ly/abc", "https://ouo.io/s/xyz?s=1"}
+	got, err := Extract(in)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractTarget(t *testing.T) {
+	tests := map[string]string{
+		"https://ouo.io/s/abc?s=https://mega.nz/file/x":           "https://mega.nz/file/x",
+		"https://href.li/?https://mega.nz/file/x":                 "https://mega.nz/file/x",
+		"https://bit.ly/abc?url=aHR0cHM6Ly9tZWdhLm56L2ZpbGUveA==": "https://mega.nz/file/x",
+		"https://bit.ly/abc?ref=twitter":                          "",
+		"https://example.com/?url=https://mega.nz/file/x":         "",
+	}
+	for link, want := range tests {
+		if got := ExtractTarget(link); got != want {
+			t.Errorf("ExtractTarget(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case r.URL.Path == "/b":
+			// Shorteners that refuse HEAD requests are retried with GET
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			http.Redirect(w, r, "/c", http.StatusFound)
+		case r.URL.Path == "/loop/x":
+			http.Redirect(w, r, "/loop/y", http.StatusFound)
+		case r.URL.Path == "/loop/y":
+			http.Redirect(w, r, "/loop/x", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/hop/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n+1), http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	defer func(n int) { MaxRedirects = n }(MaxRedirects)
+	MaxRedirects = 3
+
+	tests := []struct {
+		name, link string
+		want       []string
+	}{
+		{"chain", "/a", []string{"/a", "/b", "/c"}},
+		{"no redirect", "/c", []string{"/c"}},
+		{"loop", "/loop/x", []string{"/loop/x", "/loop/y"}},
+		{"hop limit", "/hop/0", []string{"/hop/0", "/hop/1", "/hop/2", "/hop/3"}},
+	}
+	for _, tt := range tests {
+		var want []string
+		for _, v := range tt.want {
+			want = append(want, srv.URL+v)
+		}
+		got, err := Resolve(srv.URL + tt.link)
+		if err != nil {
+			t.Fatalf("%s: Resolve() error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Resolve() = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestResolveError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.1:0/unreachable", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	chain, err := Resolve(srv.URL + "/start")
+	if err == nil {
+		t.Fatal("Resolve() error = nil, want an error for an unreachable hop")
+	}
+	// The chain up to the failing hop is returned alongside the error
+	want := []string{srv.URL + "/start", "http://127.0.0.1:0/unreachable"}
+	if !reflect.DeepEqual(chain, want) {
+		t.Errorf("Resolve() = %q, want %q", chain, want)
+	}
+}
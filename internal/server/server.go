@@ -0,0 +1,316 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package server contains the HTTP API used to control scans and query their results while Tempest runs in server
+// mode.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/internal/normalize"
+	"github.com/ax-i-om/tempest/internal/providers"
+	"github.com/ax-i-om/tempest/internal/stream"
+	"github.com/ax-i-om/tempest/internal/worker"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// MaxBodySize is the maximum size of a request body
+var MaxBodySize int64 = 1 << 20
+
+// DefaultLimit and MaxLimit are the default and maximum page sizes of the entries endpoint
+var (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+// Server serves the HTTP API
+type Server struct {
+	Store *Store
+	Hub   *stream.Hub
+	Mux   *http.ServeMux
+
+	mu      sync.Mutex
+	scanner *worker.Scanner
+}
+
+// ScanRequest represents the body of a request to start a scan
+type ScanRequest struct {
+	Sources   []string `json:"sources"`   // Names of the sources to scan, all if empty
+	Providers []string `json:"providers"` // Names of the providers to delegate to, all if empty
+}
+
+// ExtractRequest represents the body of an on-demand extraction request
+type ExtractRequest struct {
+	URL       string   `json:"url"`       // A single link to validate
+	Text      string   `json:"text"`      // Text (e.g. the contents of a paste) to extract links from
+	Source    string   `json:"source"`    // Source recorded in the entries
+	Providers []string `json:"providers"` // Names of the providers to delegate to, all if empty
+}
+
+// EntriesResponse represents a page of entries
+type EntriesResponse struct {
+	Total   int            `json:"total"`
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+	Entries []models.Entry `json:"entries"`
+}
+
+// ProviderInfo represents a provider listed by the providers endpoint
+type ProviderInfo struct {
+	Name  string   `json:"name"`
+	Hosts []string `json:"hosts"`
+}
+
+// errorResponse represents the body of an error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New returns a server with its routes registered. The store and streaming hub are registered as sinks, so they
+// receive every entry passed to handlers.Write.
+func New() *Server {
+	s := &Server{Store: &Store{}, Hub: stream.NewHub(), Mux: http.NewServeMux()}
+	handlers.Sinks = append(handlers.Sinks, s.Store, s.Hub)
+
+	s.Mux.HandleFunc("/api/status", method(http.MethodGet, s.status))
+	s.Mux.HandleFunc("/api/scan", s.scan)
+	s.Mux.HandleFunc("/api/entries", method(http.MethodGet, s.entries))
+	s.Mux.HandleFunc("/api/extract", method(http.MethodPost, s.extract))
+	s.Mux.HandleFunc("/api/providers", method(http.MethodGet, s.providers))
+	s.Mux.HandleFunc("/api/sources", method(http.MethodGet, s.sources))
+	s.Mux.HandleFunc("/api/stream", s.Hub.ServeSSE)
+	s.Mux.HandleFunc("/api/ws", s.Hub.ServeWS)
+	s.Mux.HandleFunc("/metrics", method(http.MethodGet, metrics.Handler))
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Mux.ServeHTTP(w, r)
+}
+
+// Start replaces the current scanner and starts it, unless a scan is already running
+func (s *Server) Start(scanner *worker.Scanner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scanner != nil && s.scanner.Status().Running {
+		return errors.New("a scan is already running")
+	}
+	s.scanner = scanner
+	return scanner.Start()
+}
+
+// Stop stops the running scan, if any
+func (s *Server) Stop() {
+	s.mu.Lock()
+	scanner := s.scanner
+	s.mu.Unlock()
+	if scanner != nil {
+		_ = scanner.Stop()
+	}
+}
+
+// method restricts a handler to a single HTTP method
+func method(m string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != m {
+			w.Header().Set("Allow", m)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// writeJSON writes a JSON encoded response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		handlers.LogErr(err, "failed to write api response")
+	}
+}
+
+// writeError writes a JSON encoded error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// readJSON decodes a JSON request body, rejecting unknown fields
+func readJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, MaxBodySize))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// status handles GET /api/status
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	scanner := s.scanner
+	s.mu.Unlock()
+	st := worker.Status{}
+	if scanner != nil {
+		st = scanner.Status()
+	}
+	stored, dropped := s.Store.Len()
+	writeJSON(w, http.StatusOK, struct {
+		worker.Status
+		Stored  int `json:"stored"`
+		Dropped int `json:"dropped"`
+	}{st, stored, dropped})
+}
+
+// scan handles POST /api/scan (start a scan) and DELETE /api/scan (stop the running scan)
+func (s *Server) scan(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req ScanRequest
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		scanner, err := worker.NewScanner(req.Sources, req.Providers)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err = s.Start(scanner); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, scanner.Status())
+	case http.MethodDelete:
+		s.mu.Lock()
+		scanner := s.scanner
+		s.mu.Unlock()
+		if scanner == nil || !scanner.Status().Running {
+			writeError(w, http.StatusConflict, errors.New("no scan is running"))
+			return
+		}
+		// Waits for the remaining goroutines, shouldn't take longer than 15s due to 15s httpclient.Timeout
+		if err := scanner.Stop(); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, scanner.Status())
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// entries handles GET /api/entries?service=&type=&source=&q=&offset=&limit=
+func (s *Server) entries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	offset, err := intParam(q.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid offset"))
+		return
+	}
+	limit, err := intParam(q.Get("limit"), DefaultLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid limit"))
+		return
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	f := Filter{Service: q.Get("service"), Type: q.Get("type"), Source: q.Get("source"), Query: q.Get("q")}
+	page, total := s.Store.Query(f, offset, limit)
+	writeJSON(w, http.StatusOK, EntriesResponse{Total: total, Offset: offset, Limit: limit, Entries: page})
+}
+
+// extract handles POST /api/extract, validating a link or extracting the links contained within text. The results
+// are written like those of a scan (stored, and passed to every sink).
+func (s *Server) extract(w http.ResponseWriter, r *http.Request) {
+	var req ExtractRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	text := req.Text
+	if req.URL != "" {
+		link, _ := normalize.Line(strings.TrimSpace(req.URL))
+		text = link + "\n" + text
+	}
+	if strings.TrimSpace(text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("url or text is required"))
+		return
+	}
+	scanner, err := worker.NewScanner(nil, req.Providers)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	source := req.Source
+	if source == "" {
+		source = "api"
+	}
+
+	results, err := worker.Process(text, source, scanner.Providers())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	handlers.Write(results)
+	if results == nil {
+		results = []models.Entry{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// providers handles GET /api/providers
+func (s *Server) providers(w http.ResponseWriter, r *http.Request) {
+	list := []ProviderInfo{}
+	for _, p := range providers.Providers {
+		list = append(list, ProviderInfo{Name: p.Name, Hosts: p.Hosts})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// sources handles GET /api/sources
+func (s *Server) sources(w http.ResponseWriter, r *http.Request) {
+	list := []string{}
+	for _, src := range worker.Sources {
+		list = append(list, src.Name)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// intParam parses an integer query parameter, returning def if it is empty
+func intParam(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
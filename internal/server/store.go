@@ -0,0 +1,115 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package server
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// MaxEntries is the maximum number of entries kept in memory by the store, the oldest entries are dropped first
+var MaxEntries = 100000
+
+// Store keeps discovered entries in memory so they can be queried through the API. It implements handlers.Sink.
+// Entries are kept in a ring buffer, so once MaxEntries is reached each new entry overwrites the oldest one.
+type Store struct {
+	mu      sync.RWMutex
+	entries []models.Entry // Ring buffer, the oldest entry is at next once full
+	next    int            // Position the next entry overwrites once full
+	dropped int
+}
+
+// Filter represents the criteria entries are filtered by, empty fields match every entry
+type Filter struct {
+	Service string // Matched case-insensitively
+	Type    string // Matched case-insensitively
+	Source  string // Matched exactly
+	Query   string // Matched case-insensitively against the link, title and description
+}
+
+// Send stores an entry
+func (s *Store) Send(v models.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) < MaxEntries {
+		s.entries = append(s.entries, v)
+		return
+	}
+	s.entries[s.next] = v
+	s.next = (s.next + 1) % len(s.entries)
+	s.dropped++
+}
+
+// at returns the i-th oldest stored entry
+func (s *Store) at(i int) models.Entry {
+	return s.entries[(s.next+i)%len(s.entries)]
+}
+
+// Close does nothing, entries are kept in memory until the server exits
+func (s *Store) Close() error {
+	return nil
+}
+
+// Match reports whether an entry matches a filter
+func (f Filter) Match(v models.Entry) bool {
+	if f.Service != "" && !strings.EqualFold(f.Service, v.Service) {
+		return false
+	}
+	if f.Type != "" && !strings.EqualFold(f.Type, v.Type) {
+		return false
+	}
+	if f.Source != "" && f.Source != v.Source {
+		return false
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(v.Link), q) && !strings.Contains(strings.ToLower(v.Title), q) && !strings.Contains(strings.ToLower(v.Description), q) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns a page of the entries matching a filter, newest first, alongside the total number of matching entries
+func (s *Store) Query(f Filter, offset, limit int) ([]models.Entry, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	page := []models.Entry{}
+	total := 0
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		v := s.at(i)
+		if !f.Match(v) {
+			continue
+		}
+		if total >= offset && len(page) < limit {
+			page = append(page, v)
+		}
+		total++
+	}
+	return page, total
+}
+
+// Len returns the number of stored entries and the number of entries dropped to stay within MaxEntries
+func (s *Store) Len() (int, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries), s.dropped
+}
@@ -0,0 +1,58 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestStoreRing(t *testing.T) {
+	max := MaxEntries
+	MaxEntries = 5
+	defer func() { MaxEntries = max }()
+
+	s := &Store{}
+	for i := 0; i < 12; i++ {
+		service := "Mega"
+		if i%2 == 1 {
+			service = "Gofile"
+		}
+		s.Send(models.Entry{Link: fmt.Sprint(i), Service: service})
+	}
+	if n, dropped := s.Len(); n != 5 || dropped != 7 {
+		t.Errorf("Len() = %d, %d, want 5, 7", n, dropped)
+	}
+
+	// Newest first
+	page, total := s.Query(Filter{}, 0, 10)
+	var links []string
+	for _, v := range page {
+		links = append(links, v.Link)
+	}
+	if total != 5 || fmt.Sprint(links) != "[11 10 9 8 7]" {
+		t.Errorf("Query() = %v, %d, want [11 10 9 8 7], 5", links, total)
+	}
+
+	page, total = s.Query(Filter{Service: "mega"}, 1, 1)
+	if total != 2 || len(page) != 1 || page[0].Link != "8" {
+		t.Errorf("Query(mega, 1, 1) = %v, %d, want [8], 2", page, total)
+	}
+}
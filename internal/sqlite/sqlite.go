@@ -0,0 +1,265 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package sqlite contains functions used to store entries in a SQLite database, upserting repeat links so that
+// first/last seen timestamps and sighting counts are kept up to date.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+
+	// Pure Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the tables used to store entries. Every field of models.Entry is stored in entries, except Source
+// (stored in sources/sightings) and Children (stored in children).
+const schema = `
+CREATE TABLE IF NOT EXISTS sources (
+	id         INTEGER PRIMARY KEY,
+	url        TEXT NOT NULL UNIQUE,
+	first_seen TEXT NOT NULL,
+	last_seen  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS entries (
+	id          INTEGER PRIMARY KEY,
+	link        TEXT NOT NULL UNIQUE,
+	title       TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	service     TEXT NOT NULL DEFAULT '',
+	uploaded    TEXT NOT NULL DEFAULT '',
+	mtime       TEXT NOT NULL DEFAULT '',
+	duration    TEXT NOT NULL DEFAULT '',
+	type        TEXT NOT NULL DEFAULT '',
+	mimetype    TEXT NOT NULL DEFAULT '',
+	size        TEXT NOT NULL DEFAULT '',
+	filecount   INTEGER NOT NULL DEFAULT 0,
+	owner       TEXT NOT NULL DEFAULT '',
+	resolution  TEXT NOT NULL DEFAULT '',
+	direct      TEXT NOT NULL DEFAULT '',
+	thumbnail   TEXT NOT NULL DEFAULT '',
+	downloads   INTEGER NOT NULL DEFAULT 0,
+	views       INTEGER NOT NULL DEFAULT 0,
+	members     INTEGER NOT NULL DEFAULT 0,
+	hash        TEXT NOT NULL DEFAULT '',
+	althash     TEXT NOT NULL DEFAULT '',
+	malware     TEXT NOT NULL DEFAULT '',
+	trackers    TEXT NOT NULL DEFAULT '[]',
+	obfuscation TEXT NOT NULL DEFAULT '',
+	redirects   TEXT NOT NULL DEFAULT '[]',
+	status      TEXT NOT NULL DEFAULT '',
+	first_seen  TEXT NOT NULL,
+	last_seen   TEXT NOT NULL,
+	sightings   INTEGER NOT NULL DEFAULT 1
+);
+CREATE TABLE IF NOT EXISTS sightings (
+	id        INTEGER PRIMARY KEY,
+	entry_id  INTEGER NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+	source_id INTEGER NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+	seen      TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS children (
+	id       INTEGER PRIMARY KEY,
+	entry_id INTEGER NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+	name     TEXT NOT NULL DEFAULT '',
+	link     TEXT NOT NULL DEFAULT '',
+	type     TEXT NOT NULL DEFAULT '',
+	mimetype TEXT NOT NULL DEFAULT '',
+	size     TEXT NOT NULL DEFAULT '',
+	owner    TEXT NOT NULL DEFAULT '',
+	mtime    TEXT NOT NULL DEFAULT '',
+	hash     TEXT NOT NULL DEFAULT '',
+	malware  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS sightings_entry ON sightings(entry_id);
+CREATE INDEX IF NOT EXISTS sightings_source ON sightings(source_id);
+CREATE INDEX IF NOT EXISTS children_entry ON children(entry_id);
+CREATE INDEX IF NOT EXISTS entries_service ON entries(service);
+`
+
+// upsertEntry inserts an entry, or updates the stored entry with the same link. Non-empty text fields and non-zero
+// counts replace the stored values, first_seen is kept, last_seen is updated and the sighting count is incremented.
+const upsertEntry = `
+INSERT INTO entries (link, title, description, service, uploaded, mtime, duration, type, mimetype, size, filecount, owner,
+	resolution, direct, thumbnail, downloads, views, members, hash, althash, malware, trackers, obfuscation, redirects,
+	status, first_seen, last_seen)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(link) DO UPDATE SET
+	title = COALESCE(NULLIF(excluded.title, ''), title),
+	description = COALESCE(NULLIF(excluded.description, ''), description),
+	service = COALESCE(NULLIF(excluded.service, ''), service),
+	uploaded = COALESCE(NULLIF(excluded.uploaded, ''), uploaded),
+	mtime = COALESCE(NULLIF(excluded.mtime, ''), mtime),
+	duration = COALESCE(NULLIF(excluded.duration, ''), duration),
+	type = COALESCE(NULLIF(excluded.type, ''), type),
+	mimetype = COALESCE(NULLIF(excluded.mimetype, ''), mimetype),
+	size = COALESCE(NULLIF(excluded.size, ''), size),
+	filecount = COALESCE(NULLIF(excluded.filecount, 0), filecount),
+	owner = COALESCE(NULLIF(excluded.owner, ''), owner),
+	resolution = COALESCE(NULLIF(excluded.resolution, ''), resolution),
+	direct = COALESCE(NULLIF(excluded.direct, ''), direct),
+	thumbnail = COALESCE(NULLIF(excluded.thumbnail, ''), thumbnail),
+	downloads = COALESCE(NULLIF(excluded.downloads, 0), downloads),
+	views = COALESCE(NULLIF(excluded.views, 0), views),
+	members = COALESCE(NULLIF(excluded.members, 0), members),
+	hash = COALESCE(NULLIF(excluded.hash, ''), hash),
+	althash = COALESCE(NULLIF(excluded.althash, ''), althash),
+	malware = COALESCE(NULLIF(excluded.malware, ''), malware),
+	trackers = CASE WHEN excluded.trackers = '[]' THEN trackers ELSE excluded.trackers END,
+	obfuscation = COALESCE(NULLIF(excluded.obfuscation, ''), obfuscation),
+	redirects = CASE WHEN excluded.redirects = '[]' THEN redirects ELSE excluded.redirects END,
+	status = COALESCE(NULLIF(excluded.status, ''), status),
+	first_seen = MIN(first_seen, excluded.first_seen),
+	last_seen = MAX(last_seen, excluded.last_seen),
+	sightings = sightings + 1
+RETURNING id`
+
+// upsertSource inserts a source paste, or updates the last seen timestamp of the stored source
+const upsertSource = `
+INSERT INTO sources (url, first_seen, last_seen) VALUES (?, ?, ?)
+ON CONFLICT(url) DO UPDATE SET last_seen = MAX(last_seen, excluded.last_seen)
+RETURNING id`
+
+// Open opens (creating if necessary) the SQLite database at the specified path and creates the schema
+func Open(filename string) (*sql.DB, error) {
+	// The path is escaped, so that characters such as ? and # are not taken as the start of the query/fragment
+	path := (&url.URL{Path: filename}).EscapedPath()
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// Writes are serialized by the caller, a single connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+	if _, err = db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// addedColumns contains the columns added to entries after its initial schema, alongside their definitions
+var addedColumns = []struct{ name, definition string }{
+	{"status", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// migrate adds the columns missing from the entries table of a database created by an older version
+func migrate(db *sql.DB) error {
+	for _, c := range addedColumns {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('entries') WHERE name = ?`, c.name).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			if _, err := db.Exec(`ALTER TABLE entries ADD COLUMN ` + c.name + ` ` + c.definition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Canonical returns the form of a link used as the unique key of an entry: the scheme and host are lowercased and a
+// trailing slash is removed from the path. Providers already store canonical links, so this only catches cosmetic
+// differences.
+func Canonical(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = ""
+	}
+	return u.String()
+}
+
+// Upsert stores an entry, its source paste, a sighting and its children within a single transaction. Children are
+// replaced by those of the latest sighting, unless it has none.
+func Upsert(db *sql.DB, v models.Entry) error {
+	seen := v.Seen
+	if seen == "" {
+		seen = time.Now().UTC().Format(time.RFC3339)
+	}
+	trackers, err := json.Marshal(nonNil(v.Trackers))
+	if err != nil {
+		return err
+	}
+	redirects, err := json.Marshal(nonNil(v.Redirects))
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction is committed
+	defer tx.Rollback()
+
+	var entryID int64
+	err = tx.QueryRow(upsertEntry, Canonical(v.Link), v.Title, v.Description, v.Service, v.Uploaded, v.Mtime, v.Duration, v.Type, v.MimeType, v.Size, v.FileCount, v.Owner, v.Resolution, v.Direct, v.Thumbnail, v.Downloads, v.Views, v.Members, v.Hash, v.AltHash, v.Malware, string(trackers), v.Obfuscation, string(redirects), v.Status, seen, seen).Scan(&entryID)
+	if err != nil {
+		return err
+	}
+
+	if v.Source != "" {
+		var sourceID int64
+		err = tx.QueryRow(upsertSource, v.Source, seen, seen).Scan(&sourceID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO sightings (entry_id, source_id, seen) VALUES (?, ?, ?)`, entryID, sourceID, seen)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(v.Children) > 0 {
+		if _, err = tx.Exec(`DELETE FROM children WHERE entry_id = ?`, entryID); err != nil {
+			return err
+		}
+		for _, c := range v.Children {
+			_, err = tx.Exec(`INSERT INTO children (entry_id, name, link, type, mimetype, size, owner, mtime, hash, malware) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, entryID, c.Name, c.Link, c.Type, c.MimeType, c.Size, c.Owner, c.Mtime, c.Hash, c.Malware)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// nonNil returns an empty slice in place of a nil slice, so it is encoded as [] rather than null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
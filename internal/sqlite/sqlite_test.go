@@ -0,0 +1,173 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package sqlite
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestMigrate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "old.db")
+
+	// Create a database with the entries table of an older version, which has no status column
+	db, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec(`ALTER TABLE entries DROP COLUMN status`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = Open(filename)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+	if err = Upsert(db, models.Entry{Link: "https://sendvid.com/abc", Service: "Sendvid", Status: models.Removed}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	// An empty status does not replace the stored one
+	if err = Upsert(db, models.Entry{Link: "https://sendvid.com/abc", Service: "Sendvid"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	var status string
+	var sightings int
+	if err = db.QueryRow(`SELECT status, sightings FROM entries WHERE link = ?`, "https://sendvid.com/abc").Scan(&status, &sightings); err != nil {
+		t.Fatal(err)
+	}
+	if status != models.Removed || sightings != 2 {
+		t.Errorf("status, sightings = %q, %d, want %q, 2", status, sightings, models.Removed)
+	}
+}
+
+func TestOpenEscapesPath(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "results?mode=ro#1 %41.db")
+	db, err := Open(filename)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err = Upsert(db, models.Entry{Link: "https://mega.nz/file/a"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	db.Close()
+	if _, err := os.Stat(filename); err != nil {
+		t.Errorf("database not created at %q: %v", filename, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "results")); len(files) != 0 {
+		t.Errorf("database created at %q", files)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	full := models.Entry{Source: "https://rentry.co/aaaaa/raw", Link: "HTTPS://Mega.nz/folder/abc/", Seen: "2023-02-01T00:00:00Z", Title: "title", Description: "desc", Service: "Mega", Uploaded: "2023", Mtime: "1", Duration: "01:02", Type: "Folder", MimeType: "video/mp4", Size: "1.00 GB", FileCount: 2, Owner: "owner", Resolution: "1920x1080", Direct: "https://direct", Thumbnail: "https://thumb", Downloads: 3, Views: 4, Members: 5, Hash: "hash", AltHash: "alt", Malware: "pass", Trackers: []string{"udp://a"}, Children: []models.Child{{Name: "a.txt", Link: "https://mega.nz/a", Type: "File", MimeType: "text/plain", Size: "1 B", Owner: "o", Mtime: "2", Hash: "h", Malware: "m"}, {Name: "b.txt"}}, Obfuscation: "defanged", Redirects: []string{"https://bit.ly/x"}, Status: "Online"}
+	// The same canonical link, seen earlier from another source, without metadata
+	again := models.Entry{Source: "https://rentry.co/bbbbb/raw", Link: "https://mega.nz/folder/abc", Seen: "2023-01-01T00:00:00Z", Children: []models.Child{{Name: "c.txt"}}}
+	// The first source again, later
+	later := models.Entry{Source: full.Source, Link: full.Link, Seen: "2023-03-01T00:00:00Z", Views: 10}
+	if err := Upsert(db, full); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	var c models.Child
+	err = db.QueryRow(`SELECT name, link, type, mimetype, size, owner, mtime, hash, malware FROM children WHERE name = 'a.txt'`).Scan(&c.Name, &c.Link, &c.Type, &c.MimeType, &c.Size, &c.Owner, &c.Mtime, &c.Hash, &c.Malware)
+	if err != nil || c != full.Children[0] {
+		t.Errorf("stored child = %+v (%v), want %+v", c, err, full.Children[0])
+	}
+	for _, v := range []models.Entry{again, later} {
+		if err := Upsert(db, v); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM entries`).Scan(&n)
+	if n != 1 {
+		t.Fatalf("%d entries, want 1", n)
+	}
+
+	var got models.Entry
+	var trackers, redirects, firstSeen, lastSeen string
+	var sightings int
+	err = db.QueryRow(`SELECT link, title, description, service, uploaded, mtime, duration, type, mimetype, size, filecount, owner,
+		resolution, direct, thumbnail, downloads, views, members, hash, althash, malware, trackers, obfuscation, redirects,
+		status, first_seen, last_seen, sightings FROM entries`).Scan(&got.Link, &got.Title, &got.Description, &got.Service,
+		&got.Uploaded, &got.Mtime, &got.Duration, &got.Type, &got.MimeType, &got.Size, &got.FileCount, &got.Owner,
+		&got.Resolution, &got.Direct, &got.Thumbnail, &got.Downloads, &got.Views, &got.Members, &got.Hash, &got.AltHash,
+		&got.Malware, &trackers, &got.Obfuscation, &redirects, &got.Status, &firstSeen, &lastSeen, &sightings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal([]byte(trackers), &got.Trackers)
+	json.Unmarshal([]byte(redirects), &got.Redirects)
+
+	want := full
+	want.Link, want.Views = "https://mega.nz/folder/abc", 10
+	want.Source, want.Seen, want.Children = "", "", nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stored entry = %+v, want %+v", got, want)
+	}
+	if firstSeen != again.Seen || lastSeen != later.Seen || sightings != 3 {
+		t.Errorf("first_seen, last_seen, sightings = %s, %s, %d, want %s, %s, 3", firstSeen, lastSeen, sightings, again.Seen, later.Seen)
+	}
+
+	// One source row per paste, one sighting per upsert
+	rows, err := db.Query(`SELECT s.url, COUNT(*) FROM sightings g JOIN sources s ON s.id = g.source_id GROUP BY s.url ORDER BY s.url`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perSource := make(map[string]int)
+	for rows.Next() {
+		var url string
+		var count int
+		rows.Scan(&url, &count)
+		perSource[url] = count
+	}
+	rows.Close()
+	if !reflect.DeepEqual(perSource, map[string]int{full.Source: 2, again.Source: 1}) {
+		t.Errorf("sightings per source = %v", perSource)
+	}
+
+	// Children are replaced by those of the latest sighting that has any
+	var children []string
+	rows, err = db.Query(`SELECT name FROM children ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		children = append(children, name)
+	}
+	rows.Close()
+	if !reflect.DeepEqual(children, []string{"c.txt"}) {
+		t.Errorf("children = %q, want [c.txt]", children)
+	}
+}
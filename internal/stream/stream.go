@@ -0,0 +1,213 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package stream contains a hub that pushes entries to connected clients as soon as they are written, over
+// Server-Sent Events or WebSocket. Clients can filter by service/type and resume from the last event they received.
+package stream
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Addr is the address the standalone stream server listens on (set by the --stream flag), disabled if empty
+var Addr string
+
+// AllowedOrigins lists the origins (e.g. https://dashboard.example.com, or * for any) of web pages allowed to open
+// WebSocket connections, in addition to the stream server's own origin (set by the --stream-origin flag)
+var AllowedOrigins []string
+
+// History is the number of recent events kept by a hub for clients resuming from a last event ID
+var History = 1000
+
+// Buffer is the number of events queued per client. Clients that fall further behind are disconnected, and can
+// resume from their last event ID.
+var Buffer = 256
+
+// Event represents an entry pushed to clients
+type Event struct {
+	ID    int64        `json:"id"`
+	Entry models.Entry `json:"entry"`
+}
+
+// Filter represents the services/types a client is interested in, empty lists match every entry
+type Filter struct {
+	Services []string
+	Types    []string
+}
+
+// ParseFilter reads a filter from the service and type query parameters (comma separated, or repeated)
+func ParseFilter(r *http.Request) Filter {
+	split := func(values []string) []string {
+		var out []string
+		for _, v := range values {
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					out = append(out, s)
+				}
+			}
+		}
+		return out
+	}
+	q := r.URL.Query()
+	return Filter{Services: split(q["service"]), Types: split(q["type"])}
+}
+
+// LastEventID reads the ID of the last event a client received from the Last-Event-ID header (sent by EventSource
+// when reconnecting) or the last_event_id query parameter, returning -1 if there is none
+func LastEventID(r *http.Request) int64 {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// Match reports whether an entry matches a filter
+func (f Filter) Match(v models.Entry) bool {
+	return matchAny(f.Services, v.Service) && matchAny(f.Types, v.Type)
+}
+
+// matchAny reports whether a value equals any of a list of values (case-insensitively), or the list is empty
+func matchAny(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriber represents a connected client
+type subscriber struct {
+	ch     chan Event
+	filter Filter
+}
+
+// Hub broadcasts entries to subscribers. It implements handlers.Sink.
+type Hub struct {
+	mu      sync.Mutex
+	next    int64
+	history []Event
+	subs    map[*subscriber]bool
+	closed  bool
+}
+
+// NewHub returns an empty hub
+func NewHub() *Hub {
+	return &Hub{next: 1, subs: make(map[*subscriber]bool)}
+}
+
+// Send assigns the next event ID to an entry and pushes it to every matching subscriber
+func (h *Hub) Send(v models.Entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	e := Event{ID: h.next, Entry: v}
+	h.next++
+	h.history = append(h.history, e)
+	if len(h.history) > History {
+		h.history = append([]Event(nil), h.history[len(h.history)-History:]...)
+	}
+	for s := range h.subs {
+		if !s.filter.Match(v) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			// The client is too slow, disconnect it so it can resume from its last event ID
+			delete(h.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Close disconnects every subscriber, it is safe to call more than once
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+	return nil
+}
+
+// subscribe registers a subscriber, returning the events after lastID that it missed (if they are still in the
+// history). The subscriber's channel is closed when it is disconnected.
+func (h *Hub) subscribe(f Filter, lastID int64) (*subscriber, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := &subscriber{ch: make(chan Event, Buffer), filter: f}
+	var backlog []Event
+	if lastID >= 0 {
+		for _, e := range h.history {
+			if e.ID > lastID && f.Match(e.Entry) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+	if h.closed {
+		close(s.ch)
+		return s, backlog
+	}
+	h.subs[s] = true
+	return s, backlog
+}
+
+// unsubscribe removes a subscriber, if it is still registered
+func (h *Hub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[s] {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
+
+// Clients returns the number of connected clients
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Mux returns a handler serving the SSE (/api/stream) and WebSocket (/api/ws) endpoints
+func (h *Hub) Mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/stream", h.ServeSSE)
+	mux.HandleFunc("/api/ws", h.ServeWS)
+	return mux
+}
@@ -0,0 +1,274 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// dial performs a WebSocket handshake against a test server, returning the response status and the connection
+func dial(t *testing.T, srv *httptest.Server, path string, header map[string]string) (int, *wsConn) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	headers := map[string]string{"Host": strings.TrimPrefix(srv.URL, "http://"), "Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="}
+	for k, v := range header {
+		headers[k] = v
+	}
+	req := "GET " + path + " HTTP/1.1\r\n"
+	for k, v := range headers {
+		req += k + ": " + v + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	res, err := http.ReadResponse(rw.Reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode == http.StatusSwitchingProtocols {
+		// Sample handshake from RFC 6455, section 1.3
+		if got := res.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("Sec-WebSocket-Accept = %q, want %q", got, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+		}
+	}
+	return res.StatusCode, &wsConn{conn: conn, rw: rw}
+}
+
+// writeMasked writes a masked frame, as sent by clients
+func writeMasked(t *testing.T, c *wsConn, opcode byte, payload []byte) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readEvent reads a text frame and decodes the event it carries
+func readEvent(t *testing.T, c *wsConn) Event {
+	t.Helper()
+	opcode, payload, err := c.readFrame()
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if opcode != opText {
+		t.Fatalf("opcode = %#x, want text", opcode)
+	}
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		t.Fatalf("invalid event %s: %v", payload, err)
+	}
+	return e
+}
+
+func TestHandshake(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(h.Mux())
+	defer srv.Close()
+	defer h.Close()
+
+	if status, _ := dial(t, srv, "/api/ws", nil); status != http.StatusSwitchingProtocols {
+		t.Errorf("status = %d, want 101", status)
+	}
+	if status, _ := dial(t, srv, "/api/ws", map[string]string{"Origin": srv.URL}); status != http.StatusSwitchingProtocols {
+		t.Errorf("same origin status = %d, want 101", status)
+	}
+	if status, _ := dial(t, srv, "/api/ws", map[string]string{"Origin": "https://evil.example.com"}); status != http.StatusForbidden {
+		t.Errorf("cross origin status = %d, want 403", status)
+	}
+	if status, _ := dial(t, srv, "/api/ws", map[string]string{"Sec-WebSocket-Version": "8"}); status == http.StatusSwitchingProtocols {
+		t.Errorf("unsupported version was accepted")
+	}
+
+	AllowedOrigins = []string{"https://dashboard.example.com/"}
+	defer func() { AllowedOrigins = nil }()
+	if status, _ := dial(t, srv, "/api/ws", map[string]string{"Origin": "https://dashboard.example.com"}); status != http.StatusSwitchingProtocols {
+		t.Errorf("allowed origin status = %d, want 101", status)
+	}
+	if status, _ := dial(t, srv, "/api/ws", map[string]string{"Origin": "https://evil.example.com"}); status != http.StatusForbidden {
+		t.Errorf("cross origin status = %d, want 403", status)
+	}
+}
+
+func TestWebSocketStream(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(h.Mux())
+	defer srv.Close()
+	defer h.Close()
+
+	_, c := dial(t, srv, "/api/ws?service=mega", nil)
+	// Wait for the subscription
+	for h.Clients() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	h.Send(models.Entry{Link: "https://gofile.io/d/abcdef", Service: "Gofile"})
+	h.Send(models.Entry{Link: "https://mega.nz/file/x", Service: "Mega"})
+	if e := readEvent(t, c); e.ID != 2 || e.Entry.Link != "https://mega.nz/file/x" {
+		t.Errorf("event = %+v, want the Mega entry with id 2", e)
+	}
+
+	// Pings are answered with pongs carrying the same payload
+	writeMasked(t, c, opPing, []byte("hello"))
+	opcode, payload, err := c.readFrame()
+	if err != nil || opcode != opPong || string(payload) != "hello" {
+		t.Errorf("readFrame() = %#x, %q, %v, want pong hello", opcode, payload, err)
+	}
+
+	// Close frames are echoed
+	writeMasked(t, c, opClose, []byte{0x03, 0xE8})
+	opcode, _, err = c.readFrame()
+	if err != nil || opcode != opClose {
+		t.Errorf("readFrame() = %#x, %v, want close", opcode, err)
+	}
+}
+
+func TestWebSocketResume(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(h.Mux())
+	defer srv.Close()
+	defer h.Close()
+
+	for _, l := range []string{"a", "b", "c"} {
+		h.Send(models.Entry{Link: l})
+	}
+	_, c := dial(t, srv, "/api/ws?last_event_id=1", nil)
+	for _, want := range []int64{2, 3} {
+		if e := readEvent(t, c); e.ID != want {
+			t.Errorf("event id = %d, want %d", e.ID, want)
+		}
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 65535, 65536} {
+		server, client := net.Pipe()
+		w := &wsConn{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))}
+		r := &wsConn{conn: client, rw: bufio.NewReadWriter(bufio.NewReader(client), bufio.NewWriter(client))}
+		payload := bytes.Repeat([]byte{'x'}, n)
+
+		go func() { _ = w.writeFrame(opText, payload) }()
+		// Read the header only, readFrame rejects payloads larger than maxControlPayload
+		head := make([]byte, 2)
+		if _, err := r.rw.Read(head[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.rw.Read(head[1:]); err != nil {
+			t.Fatal(err)
+		}
+		length := int(head[1])
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			_, _ = r.rw.Read(ext[:1])
+			_, _ = r.rw.Read(ext[1:])
+			length = int(ext[0])<<8 | int(ext[1])
+		case 127:
+			ext := make([]byte, 8)
+			for i := range ext {
+				_, _ = r.rw.Read(ext[i : i+1])
+			}
+			length = 0
+			for _, b := range ext {
+				length = length<<8 | int(b)
+			}
+		}
+		if head[0] != 0x80|opText || length != n {
+			t.Errorf("frame header for %d bytes = %#x, length %d", n, head[0], length)
+		}
+		server.Close()
+		client.Close()
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	r := &wsConn{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))}
+	go func() {
+		// 64 bit length far beyond maxControlPayload
+		_, _ = client.Write([]byte{0x81, 127, 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	}()
+	if _, _, err := r.readFrame(); err == nil {
+		t.Errorf("readFrame() error = nil, want error")
+	}
+}
+
+func TestSSEResume(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(h.Mux())
+	defer srv.Close()
+	defer h.Close()
+
+	for _, s := range []string{"Mega", "Gofile", "Mega"} {
+		h.Send(models.Entry{Link: "x", Service: s})
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/stream?service=mega", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() && len(ids) < 1 {
+		if id, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) != 1 || ids[0] != "3" {
+		t.Errorf("resumed ids = %v, want [3]", ids)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/stream?last_event_id=7", nil)
+	if id := LastEventID(r); id != 7 {
+		t.Errorf("LastEventID() = %d, want 7", id)
+	}
+	r.Header.Set("Last-Event-ID", "9")
+	if id := LastEventID(r); id != 9 {
+		t.Errorf("LastEventID() = %d, want 9", id)
+	}
+	if id := LastEventID(httptest.NewRequest(http.MethodGet, "/api/stream?last_event_id=x", nil)); id != -1 {
+		t.Errorf("LastEventID() = %d, want -1", id)
+	}
+}
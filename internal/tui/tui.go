@@ -0,0 +1,456 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package tui contains the interactive, full-screen terminal dashboard used to watch and control a scan
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ax-i-om/tempest/internal/globals"
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/internal/worker"
+	"github.com/ax-i-om/tempest/pkg/models"
+	"golang.org/x/term"
+)
+
+// Recent is the maximum number of recent entries kept by the dashboard
+var Recent = 1000
+
+// Refresh is the interval the dashboard is redrawn at
+var Refresh = 500 * time.Millisecond
+
+// Window is the period throughput is averaged over
+var Window = 5 * time.Second
+
+// ANSI escape sequences used to draw the dashboard
+const (
+	altScreen  = "\x1b[?1049h\x1b[?25l" // Switch to the alternate screen and hide the cursor
+	mainScreen = "\x1b[?25h\x1b[?1049l" // Show the cursor and switch back to the main screen
+	home       = "\x1b[H"               // Move the cursor to the top left corner
+	clearLine  = "\x1b[K"               // Clear the rest of the line
+	clearBelow = "\x1b[J"               // Clear the rest of the screen
+	bold       = "\x1b[1m"
+	reverse    = "\x1b[7m"
+	dim        = "\x1b[2m"
+	green      = "\x1b[32m"
+	yellow     = "\x1b[33m"
+	reset      = "\x1b[0m"
+)
+
+// Keys returned by readKeys for escape sequences
+const (
+	keyUp       = "\x1b[A"
+	keyDown     = "\x1b[B"
+	keyPageUp   = "\x1b[5~"
+	keyPageDown = "\x1b[6~"
+	keyCtrlC    = "\x03"
+)
+
+// errQuit is returned by key when the dashboard should be closed
+var errQuit = errors.New("quit")
+
+// sample is a snapshot of the counters, used to calculate throughput
+type sample struct {
+	at       time.Time
+	attempts float64
+	entries  float64
+}
+
+// Dashboard displays the live state of a scanner and the entries it finds, and controls the scanner through
+// keyboard input. It implements handlers.Sink, and must be registered as a sink to receive entries.
+type Dashboard struct {
+	scanner *worker.Scanner
+	started time.Time
+
+	mu       sync.Mutex
+	recent   []models.Entry // Recent entries, oldest first
+	total    int            // Entries received
+	selected int            // Index of the provider under the cursor
+	scroll   int            // Number of rows the entry table is scrolled back by
+	samples  []sample
+}
+
+// New returns a dashboard for a scanner
+func New(scanner *worker.Scanner) *Dashboard {
+	return &Dashboard{scanner: scanner, started: time.Now()}
+}
+
+// Send records an entry in the recent entry table, implementing handlers.Sink
+func (d *Dashboard) Send(v models.Entry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recent = append(d.recent, v)
+	if len(d.recent) > Recent {
+		d.recent = d.recent[len(d.recent)-Recent:]
+	}
+	d.total++
+	// Keep the rows in view while scrolled back
+	if d.scroll > 0 {
+		d.scroll++
+	}
+}
+
+// Close implements handlers.Sink
+func (d *Dashboard) Close() error {
+	return nil
+}
+
+// Run switches the terminal to raw mode and the alternate screen, then draws the dashboard and handles keyboard
+// input until the user quits (q or Ctrl + C). The terminal is restored before returning.
+func (d *Dashboard) Run() error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return errors.New("the dashboard requires an interactive terminal")
+	}
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in, state)
+	fmt.Print(altScreen)
+	defer fmt.Print(mainScreen)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+	ticker := time.NewTicker(Refresh)
+	defer ticker.Stop()
+
+	for {
+		width, height, err := term.GetSize(out)
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		fmt.Print(d.Render(width, height))
+
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			if err := d.key(k); err != nil {
+				return nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// readKeys reads keyboard input and sends each key (a single byte, or an escape sequence) to a channel, until
+// reading fails
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		input := buf[:n]
+		for len(input) > 0 {
+			size := 1
+			// Escape sequences (CSI) end with a byte in the range @ to ~
+			if len(input) > 2 && input[0] == 0x1b && input[1] == '[' {
+				size = 2
+				for size < len(input) && (input[size] < '@' || input[size] > '~') {
+					size++
+				}
+				if size < len(input) {
+					size++
+				}
+			}
+			keys <- string(input[:size])
+			input = input[size:]
+		}
+	}
+}
+
+// key handles a key, returning errQuit if the dashboard should be closed
+func (d *Dashboard) key(k string) error {
+	provs := d.scanner.Providers()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch k {
+	case "q", "Q", keyCtrlC:
+		return errQuit
+	case "p", "P":
+		if d.scanner.Paused() {
+			d.scanner.Resume()
+		} else {
+			d.scanner.Pause()
+		}
+	case keyUp, "k":
+		if d.selected > 0 {
+			d.selected--
+		}
+	case keyDown, "j":
+		if d.selected < len(provs)-1 {
+			d.selected++
+		}
+	case " ", "\r", "t":
+		if d.selected < len(provs) {
+			name := provs[d.selected].Name
+			_ = d.scanner.SetEnabled(name, !d.scanner.Enabled(name))
+		}
+	case "a":
+		// Enable every provider
+		for _, p := range provs {
+			_ = d.scanner.SetEnabled(p.Name, true)
+		}
+	case "n":
+		// Disable every provider but the one under the cursor
+		for i, p := range provs {
+			_ = d.scanner.SetEnabled(p.Name, i == d.selected)
+		}
+	case keyPageUp:
+		d.scroll += 10
+		if d.scroll > len(d.recent)-1 {
+			d.scroll = max(len(d.recent)-1, 0)
+		}
+	case keyPageDown:
+		d.scroll = max(d.scroll-10, 0)
+	}
+	return nil
+}
+
+// throughput records a sample of the counters and returns the number of pastes and entries per second over the
+// last Window
+func (d *Dashboard) throughput(now time.Time, attempts, entries float64) (float64, float64) {
+	d.samples = append(d.samples, sample{at: now, attempts: attempts, entries: entries})
+	for len(d.samples) > 2 && now.Sub(d.samples[1].at) >= Window {
+		d.samples = d.samples[1:]
+	}
+	first := d.samples[0]
+	elapsed := now.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+	return (attempts - first.attempts) / elapsed, (entries - first.entries) / elapsed
+}
+
+// Render draws the dashboard for a terminal of the specified size and returns it, including the escape sequences
+// needed to redraw the screen in place
+func (d *Dashboard) Render(width, height int) string {
+	st := d.scanner.Status()
+	provs := d.scanner.Providers()
+	validations := metrics.Validations.Snapshot()
+	httpErrors := metrics.HTTPErrors.Snapshot()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	pastesPerSec, entriesPerSec := d.throughput(now, float64(st.Attempts), float64(st.Entries))
+
+	var lines []string
+	add := func(format string, a ...any) {
+		lines = append(lines, fmt.Sprintf(format, a...))
+	}
+
+	// Header
+	state := green + "RUNNING" + reset
+	if st.Paused {
+		state = yellow + "PAUSED" + reset
+	} else if !st.Running {
+		state = yellow + "STOPPED" + reset
+	}
+	uptime := now.Sub(d.started).Truncate(time.Second)
+	add("%s%s%s  %s  uptime %s  sources %s", reverse+bold, pad(" TEMPEST ", 9), reset, state, uptime, strings.Join(st.Sources, ", "))
+	add("%.1f pastes/s  %.2f entries/s  in-flight %d  attempts %d  hits %d  entries %d  errors %d", pastesPerSec, entriesPerSec, globals.Wg.GetCount(), st.Attempts, st.Hits, st.Entries, st.Errors)
+	add("")
+
+	// Providers (left) and errors (right), side by side
+	panel := min(len(provs), max(height/2-4, 3))
+	top := 0
+	if d.selected >= panel {
+		top = d.selected - panel + 1
+	}
+	left := []string{bold + pad("PROVIDERS", 22) + pad("HITS", 8) + "INVALID" + reset}
+	for i := top; i < top+panel && i < len(provs); i++ {
+		p := provs[i]
+		cursor, check := "  ", "[x]"
+		if i == d.selected {
+			cursor = "> "
+		}
+		if !d.scanner.Enabled(p.Name) {
+			check = "[ ]"
+		}
+		row := cursor + check + " " + pad(p.Name, 16) + pad(fmt.Sprint(validations[p.Name+","+metrics.Valid]), 8) + fmt.Sprint(validations[p.Name+","+metrics.Invalid])
+		if i == d.selected {
+			row = reverse + row + reset
+		}
+		left = append(left, row)
+	}
+	right := []string{bold + pad("ERRORS", 16) + "COUNT" + reset}
+	for _, c := range sortedByCount(httpErrors) {
+		right = append(right, pad(c, 16)+fmt.Sprint(httpErrors[c]))
+	}
+	var failed float64
+	for _, p := range provs {
+		failed += validations[p.Name+","+metrics.Failed]
+	}
+	right = append(right, pad("delegation", 16)+fmt.Sprint(failed))
+	for i := 0; i < max(len(left), min(len(right), panel+1)); i++ {
+		l, r := "", ""
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		add("%s  %s", padVisible(l, 40), r)
+	}
+	add("")
+
+	// Recent entries, newest first
+	titleWidth := max(width-12-14-30-6, 10)
+	add("%s", bold+fmt.Sprintf("RECENT ENTRIES (%d)", d.total)+reset)
+	add("%s", bold+pad("TITLE", titleWidth)+"  "+pad("SIZE", 12)+"  "+pad("SERVICE", 14)+"  SOURCE"+reset)
+	rows := max(height-len(lines)-1, 0)
+	for i := len(d.recent) - 1 - d.scroll; i >= 0 && rows > 0; i-- {
+		v := d.recent[i]
+		// Show the link in place of a missing title
+		title := pad(v.Title, titleWidth)
+		if v.Title == "" {
+			title = dim + pad(v.Link, titleWidth) + reset
+		}
+		add("%s  %s  %s  %s", title, pad(v.Size, 12), pad(v.Service, 14), truncate(v.Source, max(width-titleWidth-12-14-6, 0)))
+		rows--
+	}
+	for ; rows > 0; rows-- {
+		add("")
+	}
+
+	// Help line
+	help := "p pause/resume  ↑/↓ select  space toggle  a enable all  n only selected  PgUp/PgDn scroll  q quit"
+	if d.scroll > 0 {
+		help = fmt.Sprintf("scrolled back %d  |  ", d.scroll) + help
+	}
+	add("%s", dim+help+reset)
+
+	// Fit the lines to the terminal
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	var b strings.Builder
+	b.WriteString(home)
+	for i, l := range lines {
+		b.WriteString(truncateVisible(l, width))
+		b.WriteString(reset + clearLine)
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString(clearBelow)
+	return b.String()
+}
+
+// sortedByCount returns the keys of a snapshot sorted by descending count
+func sortedByCount(snap map[string]float64) []string {
+	keys := make([]string, 0, len(snap))
+	for k := range snap {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if snap[keys[i]] != snap[keys[j]] {
+			return snap[keys[i]] > snap[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// pad truncates or pads a string with spaces to the specified width
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+// padVisible pads a string containing escape sequences with spaces to the specified visible width
+func padVisible(s string, width int) string {
+	if n := visibleLen(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// truncate shortens a string to the specified width, ending it with an ellipsis if it was shortened
+func truncate(s string, width int) string {
+	// Control characters (including C1 controls such as CSI) are replaced, so entries cannot inject escape sequences
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width < 1 {
+		return ""
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// truncateVisible shortens a string containing escape sequences to the specified visible width
+func truncateVisible(s string, width int) string {
+	var b strings.Builder
+	visible, escape := 0, false
+	for _, r := range s {
+		switch {
+		case r == 0x1b:
+			escape = true
+		case escape:
+			if r >= '@' && r <= '~' && r != '[' {
+				escape = false
+			}
+		default:
+			if visible == width {
+				return b.String()
+			}
+			visible++
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// visibleLen returns the number of visible characters in a string containing escape sequences
+func visibleLen(s string) int {
+	n, escape := 0, false
+	for _, r := range s {
+		switch {
+		case r == 0x1b:
+			escape = true
+		case escape:
+			if r >= '@' && r <= '~' && r != '[' {
+				escape = false
+			}
+		default:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,64 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tui
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/internal/worker"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestRenderErrors(t *testing.T) {
+	scanner, err := worker.NewScanner(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(scanner)
+	notFound := &http.Response{StatusCode: http.StatusNotFound}
+
+	// Random paste misses are not errors
+	metrics.ObserveRequest("rentry.co", time.Millisecond, notFound, nil)
+	if out := d.Render(80, 24); strings.Contains(out, "status_404") {
+		t.Errorf("paste miss shown as an error:\n%s", out)
+	}
+
+	metrics.ObserveRequest("mega.nz", time.Millisecond, notFound, nil)
+	if out := d.Render(80, 24); !strings.Contains(out, "status_404") {
+		t.Errorf("provider 404 not shown as an error:\n%s", out)
+	}
+}
+
+func TestRenderSmall(t *testing.T) {
+	scanner, err := worker.NewScanner(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(scanner)
+	d.Send(models.Entry{Link: "https://mega.nz/file/abc", Service: "Mega", Title: strings.Repeat("\x1b[2J", 10), Source: "https://x/\u009b2J\x07"})
+	for _, size := range [][2]int{{1, 1}, {20, 5}, {80, 24}} {
+		out := d.Render(size[0], size[1])
+		if strings.Contains(out, "\x1b[2J\x1b[2J") || strings.ContainsAny(out, "\u009b\x07") {
+			t.Errorf("Render(%d, %d) contains escape sequences from an entry", size[0], size[1])
+		}
+	}
+}
@@ -0,0 +1,483 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+// Package webhook contains a sink that POSTs entries as JSON to a webhook, batching them by count and time, signing
+// them with HMAC, retrying with backoff and spooling them to disk while the endpoint is unavailable.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Payload presets
+const (
+	PresetJSON    = "json"    // {"entries":[...]} (or a single entry object when BatchSize is 1)
+	PresetSlack   = "slack"   // Slack compatible incoming webhook message
+	PresetDiscord = "discord" // Discord compatible incoming webhook message
+)
+
+// SignatureHeader is the header carrying the HMAC-SHA256 signature of the request body
+const SignatureHeader = "X-Tempest-Signature"
+
+// discordEmbeds is the maximum number of embeds Discord accepts per message
+const discordEmbeds = 10
+
+// QueueSize is the number of entries that can be queued before Send spools (or drops) entries instead of queueing them
+var QueueSize = 1024
+
+// errPermanent marks a delivery failure that is not retried (e.g. a 4xx response)
+var errPermanent = errors.New("webhook rejected the payload")
+
+// Config represents the configuration of a webhook sink
+type Config struct {
+	URL        string        // Endpoint entries are POSTed to, the sink is disabled if empty
+	Preset     string        // Payload shape: json, slack or discord
+	BatchSize  int           // Number of entries sent per request, 1 sends entries one by one
+	Interval   time.Duration // Maximum time an entry waits before its (partial) batch is sent
+	Headers    []string      // Additional request headers, formatted as "Key: Value"
+	Secret     string        // Secret used to sign request bodies with HMAC-SHA256, signing is disabled if empty
+	MaxRetries int           // Number of retries of a failed request before it is spooled
+	Backoff    time.Duration // Delay before the first retry, doubled after every retry
+	SpoolDir   string        // Directory undelivered payloads are spooled to, spooling is disabled if empty
+	Timeout    time.Duration // Request timeout
+}
+
+// Default is the configuration set by the command line flags
+var Default = Config{Preset: PresetJSON, BatchSize: 1, Interval: 10 * time.Second, MaxRetries: 3, Backoff: time.Second, Timeout: 15 * time.Second}
+
+// Sink sends entries to a webhook
+type Sink struct {
+	cfg     Config
+	client  *http.Client
+	headers http.Header
+	entries chan models.Entry
+	done    chan struct{}
+	closing chan struct{} // Closed by Close, stops retries
+	mu      sync.Mutex    // Serializes deliveries and spool access
+	once    sync.Once
+	state   sync.RWMutex // Guards entries, so that it is not sent to once Close has closed it
+	closed  bool         // Whether Close has closed entries
+
+	overflow    atomic.Int64 // Entries that were spooled/dropped because the queue was full
+	seq         atomic.Int64 // Sequence number used to name spool files
+	unavailable bool         // Whether the last delivery failed
+}
+
+// New validates a configuration and starts a sink. Payloads spooled by a previous run are delivered first.
+func New(cfg Config) (*Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook url is empty")
+	}
+	switch cfg.Preset {
+	case PresetJSON, PresetSlack, PresetDiscord:
+	default:
+		return nil, fmt.Errorf("unknown webhook preset %q (expected json, slack or discord)", cfg.Preset)
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = Default.Interval
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = Default.Backoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = Default.Timeout
+	}
+
+	headers := make(http.Header)
+	for _, h := range cfg.Headers {
+		k, v, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid webhook header %q (expected \"Key: Value\")", h)
+		}
+		headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+
+	if cfg.SpoolDir != "" {
+		if err := os.MkdirAll(cfg.SpoolDir, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Sink{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, headers: headers, entries: make(chan models.Entry, QueueSize), done: make(chan struct{}), closing: make(chan struct{})}
+	go s.run()
+	return s, nil
+}
+
+// Send queues an entry to be sent. It never blocks: while the queue is full (e.g. the endpoint is down and deliveries
+// are being retried), the entry is spooled, or dropped if spooling is disabled.
+func (s *Sink) Send(v models.Entry) {
+	s.state.RLock()
+	defer s.state.RUnlock()
+	if s.closed {
+		// The sink was closed (e.g. an entry written during shutdown), keep the entry for the next run
+		s.spoolEntry(v)
+		return
+	}
+	select {
+	case s.entries <- v:
+	default:
+		s.spoolEntry(v)
+	}
+}
+
+// spoolEntry encodes an entry that could not be queued and spools (or drops) it
+func (s *Sink) spoolEntry(v models.Entry) {
+	s.overflow.Add(1)
+	payloads, err := Encode(s.cfg.Preset, []models.Entry{v}, s.cfg.BatchSize == 1)
+	if err != nil {
+		handlers.LogErr(err, "failed to encode webhook payload")
+		return
+	}
+	for _, p := range payloads {
+		s.spool(p)
+	}
+}
+
+// Overflow returns the number of entries that were spooled (or dropped) because the queue was full or the sink was
+// closed
+func (s *Sink) Overflow() int64 {
+	return s.overflow.Load()
+}
+
+// Close sends any queued entries and stops the sink. Failed deliveries are no longer retried, and once a delivery
+// fails the remaining entries are spooled (or dropped) without further attempts.
+func (s *Sink) Close() error {
+	s.once.Do(func() {
+		close(s.closing)
+		s.state.Lock()
+		s.closed = true
+		close(s.entries)
+		s.state.Unlock()
+	})
+	<-s.done
+	return nil
+}
+
+// run batches queued entries by count and time
+func (s *Sink) run() {
+	defer close(s.done)
+	s.drain()
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	var batch []models.Entry
+	for {
+		select {
+		case v, ok := <-s.entries:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, v)
+			if len(batch) >= s.cfg.BatchSize {
+				s.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			// Retry spooled payloads, then send the partial batch
+			s.drain()
+			s.flush(batch)
+			batch = nil
+		}
+	}
+}
+
+// flush encodes and delivers a batch of entries. If the spool is not empty, the batch is spooled behind it so that
+// payloads are delivered in order.
+func (s *Sink) flush(batch []models.Entry) {
+	if len(batch) == 0 {
+		return
+	}
+	payloads, err := Encode(s.cfg.Preset, batch, s.cfg.BatchSize == 1)
+	if err != nil {
+		handlers.LogErr(err, "failed to encode webhook payload")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range payloads {
+		if len(s.spooled()) > 0 {
+			s.spool(p)
+			continue
+		}
+		s.deliver(p)
+	}
+}
+
+// deliver sends a payload, spooling it if every attempt fails. While closing, payloads are spooled without being
+// sent once a delivery has failed.
+func (s *Sink) deliver(payload []byte) bool {
+	if s.unavailable && s.isClosing() {
+		s.spool(payload)
+		return false
+	}
+	err := s.post(payload)
+	s.unavailable = err != nil && !errors.Is(err, errPermanent)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, errPermanent) {
+		handlers.LogErr(err, "dropping webhook payload")
+		return true
+	}
+	handlers.LogErr(err, "failed to deliver webhook payload")
+	s.spool(payload)
+	return false
+}
+
+// isClosing returns whether Close was called
+func (s *Sink) isClosing() bool {
+	select {
+	case <-s.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// post sends a payload, retrying network errors, 429 and 5xx responses with exponential backoff. Retries stop once
+// Close is called.
+func (s *Sink) post(payload []byte) error {
+	backoff := s.cfg.Backoff
+	var err error
+	for attempt := 0; attempt <= s.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.closing:
+				return err
+			}
+			backoff *= 2
+			if backoff > time.Minute {
+				backoff = time.Minute
+			}
+		}
+
+		var wait time.Duration
+		wait, err = s.attempt(payload)
+		if err == nil || errors.Is(err, errPermanent) {
+			return err
+		}
+		if wait > backoff {
+			backoff = wait
+		}
+	}
+	return err
+}
+
+// attempt performs a single request, returning the delay requested by a Retry-After header (if any)
+func (s *Sink) attempt(payload []byte) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = s.headers.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Tempest")
+	if s.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.cfg.Secret, payload))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return 0, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		wait := time.Duration(0)
+		if sec, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(sec) * time.Second
+		}
+		return wait, fmt.Errorf("webhook responded with %s", res.Status)
+	default:
+		return 0, fmt.Errorf("%w: %s", errPermanent, res.Status)
+	}
+}
+
+// Sign returns the value of the signature header of a body: sha256= followed by the hex encoded HMAC-SHA256 of the
+// body keyed with the secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// spooled returns the paths of spooled payloads, oldest first
+func (s *Sink) spooled() []string {
+	if s.cfg.SpoolDir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(s.cfg.SpoolDir, "*.json"))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(files)
+	return files
+}
+
+// spool writes an undelivered payload to the spool directory, or drops it if spooling is disabled. The payload is
+// written to a temporary file first, so that drain never reads a partially written payload.
+func (s *Sink) spool(payload []byte) {
+	if s.cfg.SpoolDir == "" {
+		handlers.LogErr(errors.New("spooling is disabled"), "dropping undelivered webhook payload")
+		return
+	}
+	name := filepath.Join(s.cfg.SpoolDir, fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), s.seq.Add(1)%1000000))
+	if err := os.WriteFile(name+".tmp", payload, 0600); err != nil {
+		handlers.LogErr(err, "failed to spool webhook payload")
+		return
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		handlers.LogErr(err, "failed to spool webhook payload")
+	}
+}
+
+// drain delivers spooled payloads in order, stopping at the first payload that cannot be delivered
+func (s *Sink) drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.spooled() {
+		payload, err := os.ReadFile(f)
+		if err != nil {
+			handlers.LogErr(err, "failed to read spooled webhook payload")
+			return
+		}
+		err = s.post(payload)
+		s.unavailable = err != nil && !errors.Is(err, errPermanent)
+		if s.unavailable {
+			handlers.LogErr(err, "failed to deliver spooled webhook payload")
+			return
+		}
+		if err = os.Remove(f); err != nil {
+			handlers.LogErr(err, "failed to remove delivered webhook payload from spool")
+			return
+		}
+	}
+}
+
+// Encode converts a batch of entries into one or more payloads of the specified preset. With the json preset, single
+// entries are sent as an entry object if single is true.
+func Encode(preset string, batch []models.Entry, single bool) ([][]byte, error) {
+	switch preset {
+	case PresetSlack:
+		var lines []string
+		for _, v := range batch {
+			lines = append(lines, "*"+slackEscape(v.Service)+"*: <"+v.Link+"|"+slackEscape(label(v))+">"+details(v))
+		}
+		p, err := json.Marshal(map[string]string{"text": fmt.Sprintf("Tempest found %d new link(s)\n", len(batch)) + strings.Join(lines, "\n")})
+		return [][]byte{p}, err
+	case PresetDiscord:
+		var payloads [][]byte
+		for i := 0; i < len(batch); i += discordEmbeds {
+			end := i + discordEmbeds
+			if end > len(batch) {
+				end = len(batch)
+			}
+			var embeds []map[string]interface{}
+			for _, v := range batch[i:end] {
+				embed := map[string]interface{}{"title": truncate(label(v), 256), "url": v.Link, "description": truncate(v.Description, 2048)}
+				var fields []map[string]interface{}
+				for _, f := range [][2]string{{"Service", v.Service}, {"Type", v.Type}, {"Size", v.Size}, {"Source", v.Source}} {
+					if f[1] != "" {
+						fields = append(fields, map[string]interface{}{"name": f[0], "value": truncate(f[1], 1024), "inline": f[0] != "Source"})
+					}
+				}
+				embed["fields"] = fields
+				if strings.HasPrefix(v.Thumbnail, "http") {
+					embed["thumbnail"] = map[string]string{"url": v.Thumbnail}
+				}
+				embeds = append(embeds, embed)
+			}
+			p, err := json.Marshal(map[string]interface{}{"username": "Tempest", "content": fmt.Sprintf("Tempest found %d new link(s)", end-i), "embeds": embeds})
+			if err != nil {
+				return nil, err
+			}
+			payloads = append(payloads, p)
+		}
+		return payloads, nil
+	default:
+		if single && len(batch) == 1 {
+			p, err := json.Marshal(batch[0])
+			return [][]byte{p}, err
+		}
+		p, err := json.Marshal(map[string]interface{}{"count": len(batch), "entries": batch})
+		return [][]byte{p}, err
+	}
+}
+
+// label returns the title of an entry, or its link if it has none
+func label(v models.Entry) string {
+	if v.Title != "" {
+		return v.Title
+	}
+	return v.Link
+}
+
+// details returns the type and size of an entry in parentheses, if known
+func details(v models.Entry) string {
+	var d []string
+	for _, s := range []string{v.Type, v.Size} {
+		if s != "" {
+			d = append(d, s)
+		}
+	}
+	if len(d) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(d, ", ") + ")"
+}
+
+// slackEscape escapes the control characters of Slack's mrkdwn
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate shortens a string to at most n runes
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,426 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// stub is a webhook endpoint recording the requests it receives
+type stub struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	statuses []int // Statuses returned by the first requests, 200 afterwards
+}
+
+func (st *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.bodies = append(st.bodies, body)
+	st.headers = append(st.headers, r.Header.Clone())
+	if len(st.statuses) > 0 {
+		status := st.statuses[0]
+		st.statuses = st.statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+	}
+}
+
+func (st *stub) requests() [][]byte {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([][]byte(nil), st.bodies...)
+}
+
+// config returns a configuration for a stub server with short delays
+func config(url string) Config {
+	return Config{URL: url, Preset: PresetJSON, BatchSize: 1, Interval: time.Hour, MaxRetries: 3, Backoff: time.Millisecond, Timeout: time.Second}
+}
+
+func entries(links ...string) []models.Entry {
+	var batch []models.Entry
+	for _, l := range links {
+		batch = append(batch, models.Entry{Link: l, Service: "Mega"})
+	}
+	return batch
+}
+
+// waitFor waits up to 2 seconds for a condition to be met
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within 2s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newSink(t *testing.T, cfg Config) *Sink {
+	t.Helper()
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return s
+}
+
+func TestBatching(t *testing.T) {
+	st := &stub{}
+	srv := httptest.NewServer(st)
+	defer srv.Close()
+
+	cfg := config(srv.URL)
+	cfg.BatchSize = 3
+	s := newSink(t, cfg)
+	for _, v := range entries("a", "b", "c", "d", "e", "f", "g") {
+		s.Send(v)
+	}
+	s.Close()
+
+	var counts []int
+	for _, body := range st.requests() {
+		var p struct {
+			Count   int            `json:"count"`
+			Entries []models.Entry `json:"entries"`
+		}
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Fatalf("invalid payload %s: %v", body, err)
+		}
+		if p.Count != len(p.Entries) {
+			t.Errorf("count = %d, want %d", p.Count, len(p.Entries))
+		}
+		counts = append(counts, p.Count)
+	}
+	if len(counts) != 3 || counts[0] != 3 || counts[1] != 3 || counts[2] != 1 {
+		t.Errorf("batch sizes = %v, want [3 3 1]", counts)
+	}
+}
+
+func TestBatchInterval(t *testing.T) {
+	st := &stub{}
+	srv := httptest.NewServer(st)
+	defer srv.Close()
+
+	cfg := config(srv.URL)
+	cfg.BatchSize = 10
+	cfg.Interval = 20 * time.Millisecond
+	s := newSink(t, cfg)
+	defer s.Close()
+	s.Send(entries("a")[0])
+
+	// The partial batch is sent once the interval elapses
+	waitFor(t, func() bool { return len(st.requests()) == 1 })
+}
+
+func TestSignature(t *testing.T) {
+	st := &stub{}
+	srv := httptest.NewServer(st)
+	defer srv.Close()
+
+	cfg := config(srv.URL)
+	cfg.Secret = "secret"
+	cfg.Headers = []string{"Authorization: Bearer token"}
+	s := newSink(t, cfg)
+	s.Send(entries("a")[0])
+	s.Close()
+
+	if len(st.bodies) != 1 {
+		t.Fatalf("requests = %d, want 1", len(st.bodies))
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(st.bodies[0])
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := st.headers[0].Get(SignatureHeader); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+	if got := st.headers[0].Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer token")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	st := &stub{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(st)
+	defer srv.Close()
+
+	cfg := config(srv.URL)
+	cfg.SpoolDir = t.TempDir()
+	s := newSink(t, cfg)
+	s.Send(entries("a")[0])
+	// Close stops retries, wait for the delivery first
+	waitFor(t, func() bool { return len(st.requests()) == 3 })
+	s.Close()
+
+	if len(st.bodies) != 3 {
+		t.Errorf("requests = %d, want 3 (503, 429, 200)", len(st.bodies))
+	}
+	if files, _ := filepath.Glob(filepath.Join(cfg.SpoolDir, "*.json")); len(files) != 0 {
+		t.Errorf("spooled %d payloads, want 0", len(files))
+	}
+}
+
+func TestPermanentFailure(t *testing.T) {
+	st := &stub{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(st)
+	defer srv.Close()
+
+	cfg := config(srv.URL)
+	cfg.SpoolDir = t.TempDir()
+	s := newSink(t, cfg)
+	s.Send(entries("a")[0])
+	s.Close()
+
+	if len(st.bodies) != 1 {
+		t.Errorf("requests = %d, want 1", len(st.bodies))
+	}
+	if files, _ := filepath.Glob(filepath.Join(cfg.SpoolDir, "*.json")); len(files) != 0 {
+		t.Errorf("spooled %d payloads, want 0", len(files))
+	}
+}
+
+func TestSpoolDrain(t *testing.T) {
+	spool := t.TempDir()
+
+	// The endpoint is down, both payloads are spooled
+	down := &stub{statuses: []int{500, 500, 500, 500}}
+	srv := httptest.NewServer(down)
+	cfg := config(srv.URL)
+	cfg.MaxRetries = 0
+	cfg.SpoolDir = spool
+	s := newSink(t, cfg)
+	for _, v := range entries("a", "b") {
+		s.Send(v)
+	}
+	s.Close()
+	srv.Close()
+	if files, _ := filepath.Glob(filepath.Join(spool, "*.json")); len(files) != 2 {
+		t.Fatalf("spooled %d payloads, want 2", len(files))
+	}
+
+	// The endpoint is back, the spooled payloads are delivered before new ones
+	up := &stub{}
+	srv = httptest.NewServer(up)
+	defer srv.Close()
+	cfg.URL = srv.URL
+	s = newSink(t, cfg)
+	s.Send(entries("c")[0])
+	s.Close()
+
+	var links []string
+	for _, body := range up.requests() {
+		var v models.Entry
+		if err := json.Unmarshal(body, &v); err != nil {
+			t.Fatalf("invalid payload %s: %v", body, err)
+		}
+		links = append(links, v.Link)
+	}
+	if strings.Join(links, ",") != "a,b,c" {
+		t.Errorf("delivery order = %v, want [a b c]", links)
+	}
+	if files, _ := filepath.Glob(filepath.Join(spool, "*")); len(files) != 0 {
+		t.Errorf("%d files left in the spool, want 0", len(files))
+	}
+}
+
+func TestSendDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	size := QueueSize
+	QueueSize = 2
+	defer func() { QueueSize = size }()
+
+	cfg := config(srv.URL)
+	cfg.SpoolDir = t.TempDir()
+	s := newSink(t, cfg)
+
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		s.Send(models.Entry{Link: "a"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send blocked for %s", elapsed)
+	}
+	if s.Overflow() == 0 {
+		t.Errorf("Overflow() = 0, want > 0")
+	}
+	if files, _ := filepath.Glob(filepath.Join(cfg.SpoolDir, "*.json")); int64(len(files)) != s.Overflow() {
+		t.Errorf("spooled %d payloads, want %d", len(files), s.Overflow())
+	}
+}
+
+func TestCloseDoesNotRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	cfg := config(srv.URL)
+	cfg.Backoff = time.Minute
+	s := newSink(t, cfg)
+	for _, v := range entries("a", "b", "c", "d", "e") {
+		s.Send(v)
+	}
+
+	start := time.Now()
+	go func() {
+		// Let the first delivery start backing off before closing
+		time.Sleep(50 * time.Millisecond)
+		s.Close()
+	}()
+	<-s.done
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Close took %s", elapsed)
+	}
+}
+
+func TestSendAfterClose(t *testing.T) {
+	st := &stub{}
+	srv := httptest.NewServer(st)
+	defer srv.Close()
+
+	cfg := config(srv.URL)
+	cfg.SpoolDir = t.TempDir()
+	s := newSink(t, cfg)
+	s.Send(entries("a")[0])
+	s.Close()
+
+	// Entries sent after Close (and concurrently with it) are spooled rather than panicking
+	s.Send(entries("b")[0])
+	if s.Overflow() != 1 {
+		t.Errorf("Overflow() = %d, want 1", s.Overflow())
+	}
+	if files, _ := filepath.Glob(filepath.Join(cfg.SpoolDir, "*.json")); len(files) != 1 {
+		t.Errorf("spooled %d payloads, want 1", len(files))
+	}
+	if n := len(st.requests()); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+
+	s = newSink(t, config(srv.URL))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Send(models.Entry{Link: "c"})
+			}
+		}()
+	}
+	s.Close()
+	wg.Wait()
+}
+
+func TestEncodeSlack(t *testing.T) {
+	batch := []models.Entry{{Link: "https://mega.nz/folder/x", Service: "Mega", Title: "a <b> & c", Type: "Folder", Size: "1 GB"}, {Link: "https://gofile.io/d/y", Service: "Gofile"}}
+	payloads, err := Encode(PresetSlack, batch, false)
+	if err != nil || len(payloads) != 1 {
+		t.Fatalf("Encode() = %d payloads, %v", len(payloads), err)
+	}
+	var p map[string]string
+	if err := json.Unmarshal(payloads[0], &p); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	for _, want := range []string{"Tempest found 2 new link(s)", "*Mega*: <https://mega.nz/folder/x|a &lt;b&gt; &amp; c> (Folder, 1 GB)", "*Gofile*: <https://gofile.io/d/y|https://gofile.io/d/y>"} {
+		if !strings.Contains(p["text"], want) {
+			t.Errorf("text = %q, want it to contain %q", p["text"], want)
+		}
+	}
+}
+
+func TestEncodeDiscord(t *testing.T) {
+	var batch []models.Entry
+	for i := 0; i < 12; i++ {
+		batch = append(batch, models.Entry{Link: "https://mega.nz/file/x", Service: "Mega", Title: strings.Repeat("t", 300), Size: "1 GB", Thumbnail: "https://example.com/t.jpg"})
+	}
+	payloads, err := Encode(PresetDiscord, batch, false)
+	if err != nil || len(payloads) != 2 {
+		t.Fatalf("Encode() = %d payloads, %v, want 2", len(payloads), err)
+	}
+	var p struct {
+		Username string `json:"username"`
+		Embeds   []struct {
+			Title     string `json:"title"`
+			URL       string `json:"url"`
+			Thumbnail struct {
+				URL string `json:"url"`
+			} `json:"thumbnail"`
+			Fields []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal(payloads[0], &p); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if len(p.Embeds) != 10 {
+		t.Fatalf("embeds = %d, want 10", len(p.Embeds))
+	}
+	e := p.Embeds[0]
+	if len([]rune(e.Title)) > 256 || e.URL != "https://mega.nz/file/x" || e.Thumbnail.URL != "https://example.com/t.jpg" {
+		t.Errorf("embed = %+v", e)
+	}
+	if len(e.Fields) != 2 || e.Fields[0].Name != "Service" || e.Fields[1].Value != "1 GB" {
+		t.Errorf("fields = %+v", e.Fields)
+	}
+	if err := json.Unmarshal(payloads[1], &p); err != nil || len(p.Embeds) != 2 {
+		t.Errorf("second payload embeds = %d, %v, want 2", len(p.Embeds), err)
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	batch := entries("a")
+	payloads, _ := Encode(PresetJSON, batch, true)
+	var v models.Entry
+	if err := json.Unmarshal(payloads[0], &v); err != nil || v.Link != "a" {
+		t.Errorf("single payload = %s, %v", payloads[0], err)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, cfg := range []Config{{}, {URL: "http://x", Preset: "teams"}, {URL: "http://x", Preset: PresetJSON, Headers: []string{"no colon"}}} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) error = nil, want error", cfg)
+		}
+	}
+}
@@ -21,154 +21,519 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ax-i-om/tempest/internal/globals"
 	"github.com/ax-i-om/tempest/internal/handlers"
-	"github.com/ax-i-om/tempest/pkg/bunkr"
-	"github.com/ax-i-om/tempest/pkg/cloudmailru"
-	"github.com/ax-i-om/tempest/pkg/cyberdrop"
-	"github.com/ax-i-om/tempest/pkg/dood"
-	"github.com/ax-i-om/tempest/pkg/gofile"
-	"github.com/ax-i-om/tempest/pkg/googledrive"
-	"github.com/ax-i-om/tempest/pkg/mega"
+	"github.com/ax-i-om/tempest/internal/harvest"
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/internal/normalize"
+	"github.com/ax-i-om/tempest/internal/providers"
+	"github.com/ax-i-om/tempest/internal/resolve"
+	"github.com/ax-i-om/tempest/internal/stream"
+	"github.com/ax-i-om/tempest/internal/webhook"
 	"github.com/ax-i-om/tempest/pkg/models"
-	"github.com/ax-i-om/tempest/pkg/sendvid"
 )
 
-// Worker handles the randomly generated Rentry.co URL and processes the results
-func worker(source string) error {
-	// Performs a get request on the randomly generated Rentry.co URL.
+// Compile RegEx expressions for tokenization of paste contents
+var token *regexp.Regexp = regexp.MustCompile(`[^\s"'<>()\[\]]+`) // Tokens delimited by whitespace, quotes and brackets
+
+// Source represents a paste site that random paste URLs are generated for
+type Source struct {
+	Name     string        // Name of the source, used to select it
+	Host     string        // Host of the paste site, 404 responses from it are not counted as errors
+	Generate func() string // Returns a random paste URL
+}
+
+// Sources contains every paste site that can be scanned
+var Sources = []Source{
+	{Name: "rentry", Host: "rentry.co", Generate: func() string {
+		// Generate a random, 5 char long string [a-z0-9] and place it in the rentry.co string
+		return "https://rentry.co/" + handlers.TrueRand(5, "abcdefghijklmnopqrstuvwxyz0123456789") + "/raw"
+	}},
+}
+
+// Register the hosts of the paste sites, most random paste URLs do not exist
+func init() {
+	for _, src := range Sources {
+		metrics.RegisterSourceHosts(src.Host)
+	}
+}
+
+// Status represents the state and counters of a scanner
+type Status struct {
+	Running   bool     `json:"running"`
+	Paused    bool     `json:"paused"`
+	Started   string   `json:"started"`
+	Stopped   string   `json:"stopped"`
+	Sources   []string `json:"sources"`
+	Providers []string `json:"providers"`
+	Disabled  []string `json:"disabled,omitempty"` // Providers disabled at runtime
+	Attempts  int64    `json:"attempts"`           // Random paste URLs requested
+	Hits      int64    `json:"hits"`               // Pastes that existed (200 response)
+	Entries   int64    `json:"entries"`            // Entries found
+	Errors    int64    `json:"errors"`             // Failed requests/delegations
+	InFlight  int      `json:"inflight"`           // Goroutines currently running
+}
+
+// Scanner generates random paste URLs for a set of sources and delegates their contents to a set of providers
+type Scanner struct {
+	sources   []Source
+	providers []providers.Provider
+
+	attempts atomic.Int64
+	hits     atomic.Int64
+	entries  atomic.Int64
+	errors   atomic.Int64
+	paused   atomic.Bool
+
+	mu       sync.Mutex
+	disabled map[string]bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+	started  time.Time
+	stopped  time.Time
+}
+
+// NewScanner returns a scanner for the specified sources and providers (matched case-insensitively by name). All
+// sources/providers are used if none are specified.
+func NewScanner(sourceNames, providerNames []string) (*Scanner, error) {
+	s := &Scanner{disabled: make(map[string]bool)}
+	if len(sourceNames) == 0 {
+		s.sources = Sources
+	}
+	for _, name := range sourceNames {
+		found := false
+		for _, src := range Sources {
+			if strings.EqualFold(src.Name, name) {
+				s.sources = append(s.sources, src)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown source %q", name)
+		}
+	}
+	if len(providerNames) == 0 {
+		s.providers = providers.Providers
+	}
+	for _, name := range providerNames {
+		found := false
+		for _, p := range providers.Providers {
+			if strings.EqualFold(p.Name, name) {
+				s.providers = append(s.providers, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown provider %q", name)
+		}
+	}
+	return s, nil
+}
+
+// Run generates a random paste URL every 50 milliseconds and scans it in a new goroutine, until the context is
+// cancelled. No URLs are generated while the scanner is paused. It returns without waiting for the goroutines to
+// finish executing (see globals.Wg).
+func (s *Scanner) Run(cntx context.Context) {
+	for i := 0; ; i++ {
+		select {
+		case <-cntx.Done():
+			return
+		default:
+			time.Sleep(50 * time.Millisecond) // Sleeps for 50 milliseconds
+
+			// Skip this iteration while paused
+			if s.paused.Load() {
+				i--
+				continue
+			}
+
+			// Waitgroup count ++
+			globals.Wg.Add(1)
+
+			src := s.sources[i%len(s.sources)]
+			source := src.Generate()
+			go func() {
+				// When func execution is complete, subtract 1 from waitgroup
+				defer globals.Wg.Done()
+				_ = s.worker(src.Name, source)
+			}()
+		}
+	}
+}
+
+// Start runs the scanner in the background
+func (s *Scanner) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return errors.New("scanner is already running")
+	}
+	cntx, cancel := context.WithCancel(context.Background())
+	s.cancel, s.done, s.started, s.stopped = cancel, make(chan struct{}), time.Now(), time.Time{}
+	go func() {
+		s.Run(cntx)
+		// Wait for all goroutines to finish execution, shouldn't take longer than 15s due to 15s httpclient.Timeout
+		globals.Wg.Wait()
+		close(s.done)
+	}()
+	return nil
+}
+
+// Stop stops a scanner started by Start and waits for its goroutines to finish executing
+func (s *Scanner) Stop() error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if cancel == nil {
+		return errors.New("scanner is not running")
+	}
+	cancel()
+	<-done
+
+	s.mu.Lock()
+	s.cancel, s.stopped = nil, time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Pause stops the scanner from generating paste URLs until Resume is called. Pastes already being scanned are not
+// affected.
+func (s *Scanner) Pause() {
+	s.paused.Store(true)
+}
+
+// Resume resumes a paused scanner
+func (s *Scanner) Resume() {
+	s.paused.Store(false)
+}
+
+// Paused returns whether the scanner is paused
+func (s *Scanner) Paused() bool {
+	return s.paused.Load()
+}
+
+// SetEnabled enables or disables one of the scanner's providers (matched case-insensitively by name) at runtime.
+// Pastes are not delegated to disabled providers.
+func (s *Scanner) SetEnabled(name string, enabled bool) error {
+	for _, p := range s.providers {
+		if strings.EqualFold(p.Name, name) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if enabled {
+				delete(s.disabled, p.Name)
+			} else {
+				s.disabled[p.Name] = true
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown provider %q", name)
+}
+
+// Enabled returns whether one of the scanner's providers is enabled
+func (s *Scanner) Enabled(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.disabled[name]
+}
+
+// Status returns the state and counters of the scanner
+func (s *Scanner) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Status{Running: s.cancel != nil, Paused: s.paused.Load(), Attempts: s.attempts.Load(), Hits: s.hits.Load(), Entries: s.entries.Load(), Errors: s.errors.Load(), InFlight: globals.Wg.GetCount()}
+	if !s.started.IsZero() {
+		st.Started = s.started.UTC().Format(time.RFC3339)
+	}
+	if !s.stopped.IsZero() {
+		st.Stopped = s.stopped.UTC().Format(time.RFC3339)
+	}
+	for _, src := range s.sources {
+		st.Sources = append(st.Sources, src.Name)
+	}
+	for _, p := range s.providers {
+		st.Providers = append(st.Providers, p.Name)
+		if s.disabled[p.Name] {
+			st.Disabled = append(st.Disabled, p.Name)
+		}
+	}
+	return st
+}
+
+// Providers returns the providers the scanner delegates to, including disabled providers
+func (s *Scanner) Providers() []providers.Provider {
+	return s.providers
+}
+
+// active returns the providers the scanner delegates to, excluding disabled providers
+func (s *Scanner) active() []providers.Provider {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var provs []providers.Provider
+	for _, p := range s.providers {
+		if !s.disabled[p.Name] {
+			provs = append(provs, p)
+		}
+	}
+	return provs
+}
+
+// Worker handles the randomly generated paste URL (generated for the named source) and processes the results
+func (s *Scanner) worker(name, source string) error {
+	s.attempts.Add(1)
+	metrics.Attempts.Inc(name)
+	// Performs a get request on the randomly generated paste URL.
 	res, err := handlers.GetRes(source)
 	if err != nil {
+		s.errors.Add(1)
 		if !strings.Contains(err.Error(), "exceeded") {
 			handlers.LogErr(err, "worker failed to perform get request to "+source)
 		}
 		return err
 	}
-	// If a Status Code of 200 is returned, that means we randomly generated a valid Rentry.co link and can continue
+	// If a Status Code of 200 is returned, that means we randomly generated a valid paste URL and can continue
 	if res.StatusCode == 200 {
+		s.hits.Add(1)
+		metrics.Hits.Inc(name)
 		// Prepare the contents of the response to be read
 		body, err := io.ReadAll(res.Body)
 		if err != nil {
+			s.errors.Add(1)
 			handlers.LogErr(err, "worker failed to read contents of res.body")
 			return err
 		}
-		// Convert the slice of bytes to a string
-		conv := string(body)
 
-		// Create results slice
-		var results []models.Entry = nil
-
-		// Delegate the string to all specified modules
-
-		// Mega Module
-		vMega, err := mega.Delegate(conv, source)
+		// Extract and validate the links contained within the paste
+		results, err := Process(string(body), source, s.active())
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to Mega module")
+			s.errors.Add(1)
 			return err
 		}
-		results = append(results, vMega...)
+		s.entries.Add(int64(len(results)))
+		for _, v := range results {
+			metrics.Entries.Inc(v.Service)
+		}
+
+		// Call the write function to write the results depending on specified output mode/filename
+		handlers.Write(results)
+	}
 
-		// Gofile Module
-		vGofile, err := gofile.Delegate(conv, source)
+	// Close the response body, return an error/nil
+	return res.Body.Close()
+}
+
+// Process extracts and validates the links contained within the contents of a paste using the specified providers.
+// Defanged/obfuscated links are recovered and shortened links are resolved, and the results are timestamped.
+func Process(conv, source string, provs []providers.Provider) ([]models.Entry, error) {
+	// Delegate the string to all registered modules, recording the links that were extracted
+	tried := make(map[string]bool)
+	results, err := delegate(conv, source, provs, tried)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recover defanged/obfuscated links and delegate the recovered text, recording the technique used to hide
+	// any entries that were not already found. Links that were already delegated are removed from the recovered
+	// text first, so they are not validated again.
+	seen := make(map[string]bool)
+	for _, v := range results {
+		seen[v.Link] = true
+	}
+	for _, r := range normalize.Recover(conv) {
+		v, err := delegate(strip(r.Text, tried), source, provs, tried)
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to Gofile module")
-			return err
+			return nil, err
+		}
+		for _, ent := range v {
+			if seen[ent.Link] {
+				continue
+			}
+			seen[ent.Link] = true
+			ent.Obfuscation = r.Technique
+			results = append(results, ent)
 		}
-		results = append(results, vGofile...)
+		conv += "\n" + r.Text
+	}
 
-		// Sendvid Module
-		vSendvid, err := sendvid.Delegate(conv, source)
+	// Resolve links hidden behind URL shorteners/link protectors and delegate the final links, recording the
+	// redirect chain of any entries that were not already found
+	shortened, _ := resolve.Extract(conv)
+	for _, link := range shortened {
+		chain, err := resolve.Resolve(link)
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to Senvid module")
-			return err
+			handlers.LogErr(err, "worker failed to resolve shortened link "+link)
 		}
-		results = append(results, vSendvid...)
-
-		// Cyberdrop Module
-		vCyberdrop, err := cyberdrop.Delegate(conv, source)
+		if len(chain) < 2 {
+			continue
+		}
+		v, err := delegate(strip(chain[len(chain)-1], tried), source, provs, tried)
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to Cyberdrop module")
-			return err
+			return nil, err
 		}
-		results = append(results, vCyberdrop...)
+		for _, ent := range v {
+			if seen[ent.Link] {
+				continue
+			}
+			seen[ent.Link] = true
+			ent.Redirects = chain
+			results = append(results, ent)
+		}
+		conv += "\n" + chain[len(chain)-1]
+	}
+
+	// Record links to unhandled hosts, if enabled
+	if globals.HarvestFile != "" {
+		harvest.Record(conv, source)
+	}
+
+	// Timestamp the results
+	seenAt := time.Now().UTC().Format(time.RFC3339)
+	for i := range results {
+		results[i].Seen = seenAt
+	}
+	return results, nil
+}
 
-		// Bunkr Module
-		vBunkr, err := bunkr.Delegate(conv, source)
+// strip removes the links that were already delegated from a string. Only whole tokens are removed, so a link that
+// extends a delegated link (e.g. .../file/abcdef after .../file/abc) is kept intact.
+func strip(s string, tried map[string]bool) string {
+	return token.ReplaceAllStringFunc(s, func(t string) string {
+		if tried[t] {
+			return ""
+		}
+		// Punctuation following a link is not part of it
+		if trimmed := strings.TrimRight(t, ".,;:!?"); tried[trimmed] {
+			return t[len(trimmed):]
+		}
+		return t
+	})
+}
+
+// delegate passes the contents of a paste to the specified modules and returns the combined results. The outcome
+// of each link's validation is recorded in the metrics, and the extracted links are added to tried.
+func delegate(contents, source string, provs []providers.Provider, tried map[string]bool) ([]models.Entry, error) {
+	// Create results slice
+	var results []models.Entry = nil
+
+	for _, p := range provs {
+		v, err := p.Delegate(contents, source)
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to Bunkr module")
-			return err
+			metrics.Validations.Inc(p.Name, metrics.Failed)
+			handlers.LogErr(err, "worker failed during delegation to "+p.Name+" module")
+			return nil, err
+		}
+		// Record one outcome per extracted link: valid or removed if an entry was returned for it, invalid otherwise
+		outcomes := make(map[string]string)
+		links, _ := p.Extract(contents)
+		for _, l := range links {
+			tried[l] = true
+			outcomes[l] = metrics.Invalid
+		}
+		for _, ent := range v {
+			if _, ok := outcomes[ent.Link]; !ok {
+				continue
+			}
+			if ent.Status == models.Removed {
+				outcomes[ent.Link] = metrics.Removed
+			} else {
+				outcomes[ent.Link] = metrics.Valid
+			}
+		}
+		for _, outcome := range outcomes {
+			metrics.Validations.Inc(p.Name, outcome)
 		}
-		results = append(results, vBunkr...)
+		results = append(results, v...)
+	}
+	return results, nil
+}
 
-		// Google Drive Module
-		vGdrive, err := googledrive.Delegate(conv, source)
+// StartSinks registers the sinks enabled by the command line flags (e.g. the webhook sink, the stream server) and
+// starts the metrics server, if enabled
+func StartSinks() error {
+	if metrics.Addr != "" {
+		listener, err := net.Listen("tcp", metrics.Addr)
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to Google Drive module")
+			handlers.LogErr(err, "failed to start metrics server")
 			return err
 		}
-		results = append(results, vGdrive...)
-
-		// Dood Module
-		vDood, err := dood.Delegate(conv, source)
+		go func() {
+			err := http.Serve(listener, metrics.Mux())
+			handlers.LogErr(err, "metrics server stopped")
+		}()
+	}
+	if stream.Addr != "" {
+		hub := stream.NewHub()
+		listener, err := net.Listen("tcp", stream.Addr)
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to Dood module")
+			handlers.LogErr(err, "failed to start stream server")
 			return err
 		}
-		results = append(results, vDood...)
-
-		// CloudMailRu Module
-		vCMR, err := cloudmailru.Delegate(conv, source)
+		go func() {
+			err := http.Serve(listener, hub.Mux())
+			handlers.LogErr(err, "stream server stopped")
+		}()
+		handlers.Sinks = append(handlers.Sinks, hub)
+	}
+	if webhook.Default.URL != "" {
+		sink, err := webhook.New(webhook.Default)
 		if err != nil {
-			handlers.LogErr(err, "worker failed during delegation to CloudMailRu module")
+			handlers.LogErr(err, "failed to start webhook sink")
 			return err
 		}
-		results = append(results, vCMR...)
-
-		// Call the write function to write the results depending on specified output mode/filename
-		handlers.Write(results)
+		handlers.Sinks = append(handlers.Sinks, sink)
 	}
+	return nil
+}
 
-	// Close the response body, return an error/nil
-	return res.Body.Close()
+// Shutdown closes all files/flushes all writers and writes the unhandled host report, if enabled
+func Shutdown() {
+	// Close all files/flush all writers
+	handlers.Wipe()
+
+	// Write the unhandled host report, if enabled
+	if globals.HarvestFile != "" {
+		err := harvest.Write(globals.HarvestFile)
+		if err != nil {
+			handlers.LogErr(err, "failed to write harvest report to "+globals.HarvestFile)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		} else {
+			fmt.Println("Unhandled host report written to", globals.HarvestFile)
+		}
+	}
 }
 
 func run(cntx context.Context) error {
-	for {
-		select {
-		case <-cntx.Done():
-			fmt.Println("  ->  Attempting to gracefully shutdown Tempest")
-			fmt.Println("\nWaiting for", globals.Wg.GetCount(), "GoRoutines to finish execution. Please wait... (~15s)")
-
-			// Wait for all goroutines to finish execution, shouldn't take longer than 15s due to 15s httpclient.Timeout
-			globals.Wg.Wait()
-			// Close all files/flush all writers
-			handlers.Wipe()
+	scanner, err := NewScanner(nil, nil)
+	if err != nil {
+		return err
+	}
+	scanner.Run(cntx)
 
-			fmt.Println("Tempest was gracefully shut down")
+	fmt.Println("  ->  Attempting to gracefully shutdown Tempest")
+	fmt.Println("\nWaiting for", globals.Wg.GetCount(), "GoRoutines to finish execution. Please wait... (~15s)")
 
-			// Iterate through rest of func main() and eventually exit (a few lines in this case)
-			return nil
-		default:
-			time.Sleep(50 * time.Millisecond) // Sleeps for 50 milliseconds
+	// Wait for all goroutines to finish execution, shouldn't take longer than 15s due to 15s httpclient.Timeout
+	globals.Wg.Wait()
+	// Close all files/flush all writers, write the harvest report
+	Shutdown()
 
-			// Waitgroup count ++
-			globals.Wg.Add(1)
+	fmt.Println("Tempest was gracefully shut down")
 
-			go func() {
-				// When func execution is complete, subtract 1 from waitgroup
-				defer globals.Wg.Done()
-				// Generate a random, 5 char long string [a-z0-9] and place it in the rentry.co string, pass as an argument to worker()
-				_ = worker("https://rentry.co/" + handlers.TrueRand(5, "abcdefghijklmnopqrstuvwxyz0123456789") + "/raw")
-			}()
-		}
-	}
+	// Iterate through rest of func main() and eventually exit (a few lines in this case)
+	return nil
 }
 
 // Launch begins the execution of Tempest's scraping
@@ -191,6 +556,13 @@ func Launch() {
 		os.Exit(2)
 	}()
 
+	// Register the sinks enabled by the command line flags
+	if err := StartSinks(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		handlers.Wipe()
+		os.Exit(1)
+	}
+
 	// Launch run function with context
 	err := run(cntx)
 
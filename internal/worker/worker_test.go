@@ -0,0 +1,106 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package worker
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/ax-i-om/tempest/internal/metrics"
+	"github.com/ax-i-om/tempest/internal/providers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// fake returns a provider for example.com links that records the links it is asked to validate
+func fake(validated map[string]int) providers.Provider {
+	re := regexp.MustCompile(`https://example\.com/[a-z0-9]+`)
+	extract := func(s string) ([]string, error) {
+		return re.FindAllString(s, -1), nil
+	}
+	return providers.Provider{Name: "Example", Hosts: []string{"example.com"}, Extract: extract, Delegate: func(s, source string) ([]models.Entry, error) {
+		links, _ := extract(s)
+		var results []models.Entry
+		for _, l := range links {
+			validated[l]++
+			results = append(results, models.Entry{Link: l, Source: source, Service: "Example"})
+		}
+		return results, nil
+	}}
+}
+
+func TestProcess(t *testing.T) {
+	validated := make(map[string]int)
+	conv := "https://example.com/plain\nhxxps://example[.]com/defanged\nhxxps://example[.]com/plain"
+	results, err := Process(conv, "paste", []providers.Provider{fake(validated)})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Process() = %d entries, want 2: %+v", len(results), results)
+	}
+	if results[0].Link != "https://example.com/plain" || results[0].Obfuscation != "" {
+		t.Errorf("results[0] = %+v", results[0])
+	}
+	if results[1].Link != "https://example.com/defanged" || results[1].Obfuscation != "defanged" {
+		t.Errorf("results[1] = %+v", results[1])
+	}
+	// Links already delegated are not validated again when they are recovered
+	for l, n := range validated {
+		if n != 1 {
+			t.Errorf("%s validated %d times, want 1", l, n)
+		}
+	}
+	for _, v := range results {
+		if v.Seen == "" || v.Source != "paste" {
+			t.Errorf("entry %+v is missing its timestamp/source", v)
+		}
+	}
+}
+
+func TestDelegateOutcomes(t *testing.T) {
+	re := regexp.MustCompile(`https://outcome\.test/[a-z]+`)
+	p := providers.Provider{Name: "Outcome", Extract: func(s string) ([]string, error) {
+		return re.FindAllString(s, -1), nil
+	}, Delegate: func(s, source string) ([]models.Entry, error) {
+		// Several entries for a single link must not hide the invalid link
+		return []models.Entry{
+			{Link: "https://outcome.test/valid"},
+			{Link: "https://outcome.test/valid"},
+			{Link: "https://outcome.test/child"},
+			{Link: "https://outcome.test/removed", Status: models.Removed},
+		}, nil
+	}}
+	conv := "https://outcome.test/valid https://outcome.test/valid https://outcome.test/removed https://outcome.test/invalid"
+	if _, err := delegate(conv, "paste", []providers.Provider{p}, make(map[string]bool)); err != nil {
+		t.Fatal(err)
+	}
+	snap := metrics.Validations.Snapshot()
+	for outcome, want := range map[string]float64{metrics.Valid: 1, metrics.Removed: 1, metrics.Invalid: 1} {
+		if got := snap["Outcome,"+outcome]; got != want {
+			t.Errorf("%s outcomes = %v, want %v", outcome, got, want)
+		}
+	}
+}
+
+func TestStrip(t *testing.T) {
+	tried := map[string]bool{"https://example.com/a": true, "https://example.com/ab": true}
+	if got := strip("x https://example.com/ab https://example.com/a https://example.com/abc y, (https://example.com/a). https://example.com/ab\"", tried); got != "x   https://example.com/abc y, (). \"" {
+		t.Errorf("strip() = %q", got)
+	}
+}
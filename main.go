@@ -20,13 +20,14 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/ax-i-om/tempest/cmd"
 )
 
 func main() {
-	// Printing license information to the terminal
-	fmt.Println("Tempest Copyright (C) 2023 Axiom\nThis program comes with ABSOLUTELY NO WARRANTY.\nThis is free software, and you are welcome to redistribute it\nunder certain conditions.")
+	// Printing license information to the terminal (stderr, so it does not mix with output that is piped)
+	fmt.Fprintln(os.Stderr, "Tempest Copyright (C) 2023 Axiom\nThis program comes with ABSOLUTELY NO WARRANTY.\nThis is free software, and you are welcome to redistribute it\nunder certain conditions.")
 
 	cmd.Execute()
 }
@@ -0,0 +1,202 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package catbox contains functions that can be used to accurately extract and validate Catbox links.
+package catbox
+
+import (
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var cLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(files\.catbox\.moe/[a-z0-9]{6}\.[a-zA-Z0-9]{1,5}|catbox\.moe/c/[a-z0-9]{6})`) // Extract Catbox file and album links
+var roughTitle *regexp.Regexp = regexp.MustCompile(`<h1[^>]*>(.*?)</h1>`)                                                                    // Extract album title
+var roughDesc *regexp.Regexp = regexp.MustCompile(`<p class="subtext">(.*?)</p>`)                                                            // Extract album description
+var albumFile *regexp.Regexp = regexp.MustCompile(`https://files\.catbox\.moe/[a-z0-9]{6}\.[a-zA-Z0-9]{1,5}`)                                // Extract album files
+var imageExt *regexp.Regexp = regexp.MustCompile(`\.(png|jpe?g|gif|webp)$`)                                                                  // Identify image files
+
+// Extract returns a slice of all Catbox links contained within a string, if any.
+func Extract(res string) ([]string, error) {
+	// Return all Catbox links found within an http response
+	return cLink.FindAllString(res, -1), nil
+}
+
+// ExtractTitle takes the body response/contents of a Catbox album page (raw source/html (formatted as string)) as
+// an argument and returns the album's title as a string.
+func ExtractTitle(catboxContents string) string {
+	if m := roughTitle.FindStringSubmatch(catboxContents); m != nil {
+		return html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	return ""
+}
+
+// ExtractDescription takes the body response/contents of a Catbox album page (raw source/html (formatted as string))
+// as an argument and returns the album's description as a string.
+func ExtractDescription(catboxContents string) string {
+	if m := roughDesc.FindStringSubmatch(catboxContents); m != nil {
+		return html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	return ""
+}
+
+// ExtractFiles takes the body response/contents of a Catbox album page (raw source/html (formatted as string)) as
+// an argument and returns the direct links of the files contained within the album, without duplicates.
+func ExtractFiles(catboxContents string) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, v := range albumFile.FindAllString(catboxContents, -1) {
+		if !seen[v] {
+			seen[v] = true
+			files = append(files, v)
+		}
+	}
+	return files
+}
+
+// ExtractThumbnail takes a slice of Catbox file links as an argument. Catbox albums do not have a dedicated thumbnail,
+// so ExtractThumbnail() instead returns the first image file, as this can grant more insight if other metadata is
+// misleading/inconclusive.
+func ExtractThumbnail(files []string) string {
+	for _, v := range files {
+		if imageExt.MatchString(v) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Availability identifies whether a Catbox file/album is online, was removed or never existed. File links are checked
+// with a HEAD request so that the file itself is not downloaded. It returns one of models.Online, models.Removed or
+// models.NotFound, the contents of the page (album links only), the response headers and an error which will be nil
+// if successful.
+func Availability(x string) (string, string, http.Header, error) {
+	if strings.Contains(x, "files.catbox.moe") {
+		res, err := handlers.HeadRes(x)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return status(res.StatusCode, ""), "", res.Header, res.Body.Close()
+	}
+
+	// Perform a GET request using the Catbox album URL
+	res, err := handlers.GetRes(x)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return status(res.StatusCode, string(body)), string(body), res.Header, res.Body.Close()
+}
+
+// status converts the status code and contents of a Catbox response into an availability state. Catbox responds
+// with 451 (Unavailable For Legal Reasons) or 410 (Gone) for files that were taken down.
+func status(code int, contents string) string {
+	switch {
+	case code == 451 || code == 410:
+		return models.Removed
+	case code == 200 && !strings.Contains(contents, "No album found"):
+		return models.Online
+	default:
+		return models.NotFound
+	}
+}
+
+// Validate performs a request to the Catbox URL and uses the response to identify its validity
+func Validate(x string) (bool, error) {
+	state, _, _, err := Availability(x)
+	return state == models.Online, err
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Catbox links found within the response (if any) and an error.
+// Links to removed content are returned too, without metadata and with Status set to models.Removed.
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Catbox links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on catbox delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Catbox links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Catbox links
+		var results []models.Entry = nil
+		// Loop through each Catbox link within the slice
+		for _, v := range x {
+			// Call the Availability function in order to check whether or not the link is valid
+			state, contents, header, err := Availability(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on catbox delegate attempt to call availability")
+				continue
+			}
+			// If the link is online, extract its metadata and append the link to the specified results slice.
+			if state == models.Online {
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Catbox", Status: models.Online}
+
+				if strings.Contains(v, "/c/") {
+					files := ExtractFiles(contents)
+					for _, f := range files {
+						ent.Children = append(ent.Children, models.Child{Name: path.Base(f), Link: f, Type: "File", MimeType: mime.TypeByExtension(path.Ext(f))})
+					}
+					ent.Title = ExtractTitle(contents)
+					ent.Description = ExtractDescription(contents)
+					ent.Thumbnail = ExtractThumbnail(files)
+					ent.FileCount = len(files)
+					ent.Type = "Folder"
+				} else {
+					ent.Title = path.Base(v)
+					ent.MimeType, _, _ = mime.ParseMediaType(header.Get("Content-Type"))
+					if size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+						ent.Size = handlers.FormatBytes(size)
+					}
+					if imageExt.MatchString(v) {
+						ent.Thumbnail = v
+					}
+					ent.Type = "File"
+				}
+
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			} else if state == models.Removed {
+				// Removed content is reported without metadata, so that takedowns can be told apart from invalid links
+				results = append(results, models.Entry{Source: source, Link: v, Service: "Catbox", Status: models.Removed})
+			} else {
+				handlers.LogInfo("catbox link " + v + " is offline: " + state)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,43 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package catbox
+
+import (
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		code     int
+		contents string
+		want     string
+	}{
+		{200, "", models.Online},
+		{200, "<h1>No album found</h1>", models.NotFound},
+		{404, "", models.NotFound},
+		{410, "", models.Removed},
+		{451, "", models.Removed},
+	}
+	for _, tt := range tests {
+		if got := status(tt.code, tt.contents); got != tt.want {
+			t.Errorf("status(%d, %q) = %q, want %q", tt.code, tt.contents, got, tt.want)
+		}
+	}
+}
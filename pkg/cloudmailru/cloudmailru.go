@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -34,6 +35,12 @@ import (
 var rLink *regexp.Regexp = regexp.MustCompile(`(https|http)://cloud.mail.ru/public/[a-zA-Z0-9]{4}/[a-zA-Z0-9]{9}`)
 var rInfo *regexp.Regexp = regexp.MustCompile(`"serverSideFolders":{(.*?)"DISPATCHERS":`)
 
+// MaxDepth is the maximum number of folder levels traversed when listing the contents of a public folder
+var MaxDepth = 3
+
+// MaxChildren is the maximum number of child records collected from a single public folder
+var MaxChildren = 500
+
 // Extract returns a slice of all cloudmailru links contained within a string, if any.
 func Extract(res string) ([]string, error) {
 	return rLink.FindAllString(res, -1), nil // Return all cloudmailru links found within an http response
@@ -51,6 +58,71 @@ func ExtractInfo(cmailrContents string) (*models.CMRInfo, error) {
 	return info, nil
 }
 
+// ExtractFolder uses the cloud.mail.ru API to retrieve a single level of a public folder's contents. It takes the
+// folder's weblink (e.g. AbCd/123456789 or AbCd/123456789/Subfolder) as an argument and returns the listing alongside
+// an error, which will be nil if successful.
+func ExtractFolder(weblink string) ([]models.CMRInfo, error) {
+	res, err := handlers.GetRes("https://cloud.mail.ru/api/v2/folder?weblink=" + url.QueryEscape(weblink) + "&offset=0&limit=" + fmt.Sprint(MaxChildren))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := new(struct {
+		Body models.CMRInfo `json:"body"`
+	})
+	err = json.Unmarshal(body, &folder)
+	if err != nil {
+		return nil, err
+	}
+	return folder.Body.List, nil
+}
+
+// ExtractChildren recursively traverses a public folder's listing and returns its contents as a flat slice of
+// child records. Subfolders that were not included in the page metadata are retrieved via ExtractFolder(). The
+// traversal stops after MaxDepth levels or once MaxChildren records have been collected.
+func ExtractChildren(cmr *models.CMRInfo) []models.Child {
+	list := cmr.List
+	if len(list) == 0 && cmr.Kind == "folder" {
+		sub, err := ExtractFolder(cmr.Weblink)
+		if err != nil {
+			handlers.LogErr(err, "error occurred on cloudmailru attempt to list folder "+cmr.Weblink)
+			return nil
+		}
+		list = sub
+	}
+	var children []models.Child
+	traverse(list, 1, &children)
+	return children
+}
+
+// traverse appends each item in list to children, descending into subfolders until MaxDepth/MaxChildren is reached
+func traverse(list []models.CMRInfo, depth int, children *[]models.Child) {
+	for _, v := range list {
+		if len(*children) >= MaxChildren {
+			return
+		}
+		*children = append(*children, models.Child{Name: v.Name, Link: "https://cloud.mail.ru/public/" + v.Weblink, Type: v.Type, Size: fmt.Sprint(v.Size), Mtime: fmt.Sprint(v.Mtime), Hash: v.Hash, Malware: v.Malware.Status})
+		if v.Kind == "folder" && depth < MaxDepth {
+			sub := v.List
+			if len(sub) == 0 {
+				var err error
+				sub, err = ExtractFolder(v.Weblink)
+				if err != nil {
+					handlers.LogErr(err, "error occurred on cloudmailru attempt to list folder "+v.Weblink)
+					continue
+				}
+			}
+			traverse(sub, depth+1, children)
+		}
+	}
+}
+
 // Validate takes a Gofile link/URL and checks certain metadata patterns to identify whether or not the link is valid/online.
 func Validate(x string) (bool, string, error) {
 	// Perform a GET request using the Gofile URL
@@ -105,6 +177,15 @@ func Delegate(res, source string) ([]models.Entry, error) {
 
 				// Create type Entry and specify the respective values
 				ent := models.Entry{Source: source, Link: v, Service: "CloudMailRu", Title: cmr.Name, Size: fmt.Sprint(cmr.Size), Type: cmr.Type, Mtime: fmt.Sprint(cmr.Mtime), Hash: cmr.Hash, Malware: cmr.Malware.Status}
+				// List the contents of public folders
+				if cmr.Kind == "folder" {
+					ent.Children = ExtractChildren(cmr)
+					for _, c := range ent.Children {
+						if c.Type != "folder" {
+							ent.FileCount++
+						}
+					}
+				}
 				// Append the entry to the results slice to be returned to the main runner
 				results = append(results, ent)
 			}
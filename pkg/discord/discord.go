@@ -0,0 +1,137 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package discord contains functions that can be used to accurately extract and validate Discord invite links.
+package discord
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var dLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(www\.)?(discord\.gg|discord\.com/invite|discordapp\.com/invite)/([a-zA-Z0-9-]{2,32})`) // Extract Discord invite links
+
+// Extract returns a slice of all Discord invite links contained within a string, if any. Links are converted to the
+// discord.gg domain.
+func Extract(res string) ([]string, error) {
+	var links []string
+	for _, m := range dLink.FindAllStringSubmatch(res, -1) {
+		links = append(links, "https://discord.gg/"+m[4])
+	}
+	// Return all Discord invite links found within an http response
+	return links, nil
+}
+
+// ExtractCode returns the invite code of a Discord invite link
+func ExtractCode(link string) string {
+	m := dLink.FindStringSubmatch(link)
+	if m == nil {
+		return ""
+	}
+	return m[4]
+}
+
+// ExtractInfo takes the contents of the body response from the Discord invite endpoint and unmarshals it, returning
+// the metadata as type *models.DCInvite alongside an error
+func ExtractInfo(discordContents string) (*models.DCInvite, error) {
+	info := new(models.DCInvite)
+	err := json.Unmarshal([]byte(discordContents), &info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Validate takes a Discord invite link and queries the public invite endpoint to identify whether or not the invite is
+// valid. Expired/invalid invites respond with a 404 (Unknown Invite). It returns the contents of the API response
+// alongside its validity.
+func Validate(x string) (bool, string, error) {
+	// Perform a GET request using the Discord API
+	res, err := handlers.GetRes("https://discord.com/api/v10/invites/" + ExtractCode(x) + "?with_counts=true")
+	if err != nil {
+		return false, "", err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	err = res.Body.Close()
+
+	if res.StatusCode == 200 {
+		return true, string(body), err
+	} else {
+		return false, string(body), err
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Discord invite links found within the response (if any) or nil, and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Discord invite links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on discord delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Discord invite links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Discord invite links
+		var results []models.Entry = nil
+		// Loop through each Discord invite link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on discord delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				dc, err := ExtractInfo(contents)
+				if err != nil {
+					handlers.LogErr(err, "error occurred on discord delegate attempt to extract invite metadata")
+					continue
+				}
+
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Discord", Title: dc.Guild.Name, Description: dc.Guild.Description, Type: "Server", Members: dc.ApproximateMemberCount}
+				if dc.Guild.Icon != "" {
+					ent.Thumbnail = "https://cdn.discordapp.com/icons/" + dc.Guild.ID + "/" + dc.Guild.Icon + ".png"
+				}
+				// Group DM invites have no guild, use the channel name instead
+				if dc.Guild.ID == "" {
+					ent.Title = dc.Channel.Name
+					ent.Type = "Group"
+				}
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
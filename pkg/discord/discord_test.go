@@ -0,0 +1,64 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package discord
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	in := `join https://discord.gg/abc-DEF1 or <a href="https://discord.com/invite/Tempest">here</a>
+		https://www.discordapp.com/invite/xyz123 https://discord.com/channels/123/456`
+	want := []string{"https://discord.gg/abc-DEF1", "https://discord.gg/Tempest", "https://discord.gg/xyz123"}
+	got, err := Extract(in)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractCode(t *testing.T) {
+	tests := map[string]string{
+		"https://discord.gg/abc-DEF1":         "abc-DEF1",
+		"https://discord.com/invite/Tempest":  "Tempest",
+		"https://discord.com/channels/123/45": "",
+	}
+	for link, want := range tests {
+		if got := ExtractCode(link); got != want {
+			t.Errorf("ExtractCode(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestExtractInfo(t *testing.T) {
+	info, err := ExtractInfo(`{"type":0,"code":"Tempest","guild":{"id":"1234","name":"Tempest","description":"A server","icon":"a1b2"},
+		"channel":{"id":"5678","name":"general","type":0},"approximate_member_count":4321,"approximate_presence_count":12}`)
+	if err != nil {
+		t.Fatalf("ExtractInfo() error = %v", err)
+	}
+	if info.Code != "Tempest" || info.Guild.ID != "1234" || info.Guild.Name != "Tempest" || info.Guild.Description != "A server" ||
+		info.Guild.Icon != "a1b2" || info.Channel.Name != "general" || info.ApproximateMemberCount != 4321 {
+		t.Errorf("ExtractInfo() = %+v", *info)
+	}
+	if _, err := ExtractInfo(`<html>`); err == nil {
+		t.Error("ExtractInfo(html) error = nil, want an error")
+	}
+}
@@ -20,16 +20,26 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package dood
 
 import (
+	"html"
 	"io"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/ax-i-om/tempest/internal/handlers"
 	"github.com/ax-i-om/tempest/pkg/models"
 )
 
-// Compile the RegEx expression to be used in the identification and extraction of the Bunkr links
+// Compile RegEx expressions for extraction of links/metadata
 var dLink *regexp.Regexp = regexp.MustCompile("(https|http)://(doods|dood).(la|re|wf|so|yt|pm|sh|to|ws|one|watch|pro|stream)/((f/[a-z0-9]{10})|((d/[a-z0-9]{32}|(d/[a-z0-9]{31})|(d/[a-z0-9]{12})))|e/[a-z0-9]{12})")
+var vID *regexp.Regexp = regexp.MustCompile(`/(d|e)/([a-z0-9]{12})$`)                                           // Extract video ID from embed/download links
+var roughTitle *regexp.Regexp = regexp.MustCompile(`<title>(.*?)</title>`)                                      // Extract title
+var headTitle *regexp.Regexp = regexp.MustCompile(`<div class="title-wrap">\s*<h4>(.*?)</h4>`)                  // Extract title from the download page
+var roughLength *regexp.Regexp = regexp.MustCompile(`<div class="length">.*?(\d{1,2}:\d{2}(:\d{2})?)\s*</div>`) // Extract length
+var roughSize *regexp.Regexp = regexp.MustCompile(`<div class="size">.*?(\d+(?:\.\d+)?\s*[KMGTP]?B)\s*</div>`)  // Extract size
+var roughUploaded *regexp.Regexp = regexp.MustCompile(`<div class="uploadate">.*?</i>\s*(.*?)\s*</div>`)        // Extract upload date
+var ogImage *regexp.Regexp = regexp.MustCompile(`<meta property="og:image" content="(.*?)"`)                    // Extract poster image
+var poster *regexp.Regexp = regexp.MustCompile(`poster="(https?://.*?)"`)                                       // Extract poster image from the embed player
 
 // Extract returns a slice of all Dood links contained within a string, if any.
 func Extract(res string) ([]string, error) {
@@ -37,25 +47,104 @@ func Extract(res string) ([]string, error) {
 	return dLink.FindAllString(res, -1), nil
 }
 
-// Validate performs a GET request to the Dood URL and uses the response status code to identify its validity
-func Validate(x string) (bool, error) {
+// Canonical maps the /e/ (embed) and /d/ (download) forms of a Dood video link to the same canonical /d/ link,
+// so that both forms of the same video are only processed once. Folder links are returned unchanged.
+func Canonical(link string) string {
+	m := vID.FindStringSubmatch(link)
+	if m == nil {
+		return link
+	}
+	return "https://doods.pro/d/" + m[2]
+}
+
+// ExtractID returns the canonical video ID of a Dood embed/download link, or an empty string if the link is not a video.
+func ExtractID(link string) string {
+	m := vID.FindStringSubmatch(link)
+	if m == nil {
+		return ""
+	}
+	return m[2]
+}
+
+// ExtractTitle takes the body response/contents of a Dood page (raw source/html (formatted as string)) as
+// an argument and returns the video's title as a string.
+func ExtractTitle(doodContents string) string {
+	if m := headTitle.FindStringSubmatch(doodContents); m != nil {
+		return html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	eTitle := roughTitle.FindString(doodContents)            // Extract rough title
+	eTitle = strings.ReplaceAll(eTitle, `<title>`, ``)       // Strip opening tag
+	eTitle = strings.ReplaceAll(eTitle, `</title>`, ``)      // Strip closing tag
+	eTitle = strings.ReplaceAll(eTitle, ` - DoodStream`, ``) // Strip unnecessary text
+	return html.UnescapeString(eTitle)
+}
+
+// ExtractLength takes the body response/contents of a Dood download page (raw source/html (formatted as string)) as
+// an argument and returns the video's length (e.g. 01:23:45) as a string.
+func ExtractLength(doodContents string) string {
+	if m := roughLength.FindStringSubmatch(doodContents); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ExtractSize takes the body response/contents of a Dood download page (raw source/html (formatted as string)) as
+// an argument and returns the video's file size as a string.
+func ExtractSize(doodContents string) string {
+	if m := roughSize.FindStringSubmatch(doodContents); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ExtractUploadDate takes the body response/contents of a Dood download page (raw source/html (formatted as string))
+// and a dateFormat string as it's arguments. The dateFormat string will be used to format a time.Time into a more
+// favorable format. The default dateFormat used by tempest is "Jan 02, 2006". It will return a date in string format,
+// which has been formatted in accordance with the specified dateFormat, and an error which will be nil if successful.
+func ExtractUploadDate(doodContents string, dateFormat string) (string, error) {
+	m := roughUploaded.FindStringSubmatch(doodContents)
+	if m == nil {
+		return "", nil
+	}
+	parsed, err := time.Parse("Jan 2, 2006", m[1]) // Convert string to *time.Time
+	if err != nil {
+		return "", err
+	}
+	return parsed.Format(dateFormat), nil // Format the date based on the format specified in dateFormat and return as string
+}
+
+// ExtractThumbnail takes the body response/contents of a Dood page (raw source/html (formatted as string)) as
+// an argument and returns the video's poster image URL as a string.
+func ExtractThumbnail(doodContents string) string {
+	if m := ogImage.FindStringSubmatch(doodContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	if m := poster.FindStringSubmatch(doodContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// Validate performs a GET request to the Dood URL and uses the response contents to identify its validity. It returns
+// the contents of the page alongside its validity.
+func Validate(x string) (bool, string, error) {
 	// Perform a GET request using the Dood URL
 	res, err := handlers.GetRes(x)
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
 
 	// Prepare the contents of the response to be read
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
 
 	// Read the response, if the title contains the below specified string, then the Dood link is not online.
 	if !strings.Contains(string(body), "<title>Video not found | DoodStream</title>") || res.StatusCode == 403 {
-		return true, res.Body.Close()
+		return true, string(body), res.Body.Close()
 	} else {
-		return false, res.Body.Close()
+		return false, string(body), res.Body.Close()
 	}
 }
 
@@ -75,7 +164,7 @@ func Convert(res string) string {
 	return post
 }
 
-// Delegate takes a string as an argument and returns a slice of valid Senvid links found within the response (if any) or nil, and an error
+// Delegate takes a string as an argument and returns a slice of valid Dood links found within the response (if any) or nil, and an error
 func Delegate(res, source string) ([]models.Entry, error) {
 	// Use Convert() to convert all Dood link domains to doods.pro (the currently active one)
 	c := Convert(res)
@@ -89,10 +178,18 @@ func Delegate(res, source string) ([]models.Entry, error) {
 	if len(x) > 0 {
 		// Create a new, empty slice where we will append any valid Dood links
 		var results []models.Entry = nil
+		// Keep track of the canonical links that were already processed
+		seen := make(map[string]bool)
 		// Loop through each Dood link within the slice
 		for _, v := range x {
+			// Map embed and download links of the same video to one canonical link
+			v = Canonical(v)
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
 			// Call the Validate function in order to check whether or not the link is valid
-			x, err := Validate(v)
+			x, contents, err := Validate(v)
 			if err != nil {
 				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
 				handlers.LogErr(err, "error occurred on dood delegate attempt to call validate")
@@ -100,8 +197,25 @@ func Delegate(res, source string) ([]models.Entry, error) {
 			}
 			// If x, the bool return by Validate(), is true: output the result to the terminal and append the link to the specified results slice.
 			if x {
+				// Remove newline and tabs from content
+				contents = strings.ReplaceAll(contents, "\n", ``)
+				contents = strings.ReplaceAll(contents, "\t", ``)
+
 				// Create type Entry and specify the respective values
-				ent := models.Entry{Source: source, Link: v, Service: "Dood", Type: "File"}
+				ent := models.Entry{Source: source, Link: v, Service: "Dood", Title: ExtractTitle(contents), Thumbnail: ExtractThumbnail(contents)}
+
+				if strings.Contains(v, "/f/") {
+					ent.Type = "Folder"
+				} else {
+					ent.Type = "File"
+					ent.Duration = ExtractLength(contents) // Extract length
+					ent.Size = ExtractSize(contents)       // Extract size
+					// Extract upload date, the entry is kept without one if the date cannot be parsed
+					ent.Uploaded, err = ExtractUploadDate(contents, "Jan 02, 2006")
+					if err != nil {
+						handlers.LogErr(err, "error occurred on dood delegate attempt to extract the upload date of "+v)
+					}
+				}
 				// Append the entry to the results slice to be returned to the main runner
 				results = append(results, ent)
 			}
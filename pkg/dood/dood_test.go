@@ -0,0 +1,103 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package dood
+
+import (
+	"reflect"
+	"testing"
+)
+
+// page is a trimmed Dood download page, with the newlines and tabs removed as Delegate does
+const page = `<html><head><title>Holiday clip - DoodStream</title><meta property="og:image" content="https://img.doodcdn.co/snaps/abc.jpg?a=1&amp;b=2"></head>` +
+	`<body><div class="title-wrap">  <h4>Holiday &amp; clip.mp4</h4></div>` +
+	`<div class="length"><i class="icon-clock"></i> 01:02:03 </div>` +
+	`<div class="size"><i class="icon-size"></i> 245.6 MB </div>` +
+	`<div class="uploadate"><i class="icon-date"></i> Mar 5, 2024 </div></body></html>`
+
+func TestExtract(t *testing.T) {
+	in := `https://dood.la/d/abcdefghij12 https://doods.pro/e/0123456789ab https://dood.re/f/abcdefghij
+		https://dood.example/d/abcdefghij12 https://dood.la/x/abcdefghij12`
+	want := []string{"https://dood.la/d/abcdefghij12", "https://doods.pro/e/0123456789ab", "https://dood.re/f/abcdefghij"}
+	got, err := Extract(in)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		link, canonical, id string
+	}{
+		{"https://dood.la/e/abcdefghij12", "https://doods.pro/d/abcdefghij12", "abcdefghij12"},
+		{"https://doods.pro/d/abcdefghij12", "https://doods.pro/d/abcdefghij12", "abcdefghij12"},
+		{"https://dood.re/f/abcdefghij", "https://dood.re/f/abcdefghij", ""},
+	}
+	for _, tt := range tests {
+		if got := Canonical(tt.link); got != tt.canonical {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.link, got, tt.canonical)
+		}
+		if got := ExtractID(tt.link); got != tt.id {
+			t.Errorf("ExtractID(%q) = %q, want %q", tt.link, got, tt.id)
+		}
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	if got := ExtractTitle(page); got != "Holiday & clip.mp4" {
+		t.Errorf("ExtractTitle() = %q", got)
+	}
+	if got := ExtractTitle(`<title>Embedded &amp; clip - DoodStream</title>`); got != "Embedded & clip" {
+		t.Errorf("ExtractTitle(embed) = %q", got)
+	}
+	if got := ExtractLength(page); got != "01:02:03" {
+		t.Errorf("ExtractLength() = %q", got)
+	}
+	if got := ExtractSize(page); got != "245.6 MB" {
+		t.Errorf("ExtractSize() = %q", got)
+	}
+	if got := ExtractThumbnail(page); got != "https://img.doodcdn.co/snaps/abc.jpg?a=1&b=2" {
+		t.Errorf("ExtractThumbnail() = %q", got)
+	}
+	if got := ExtractThumbnail(`<video poster="https://img.doodcdn.co/p.jpg">`); got != "https://img.doodcdn.co/p.jpg" {
+		t.Errorf("ExtractThumbnail(embed) = %q", got)
+	}
+}
+
+func TestExtractUploadDate(t *testing.T) {
+	got, err := ExtractUploadDate(page, "2006-01-02")
+	if err != nil || got != "2024-03-05" {
+		t.Errorf("ExtractUploadDate() = %q, %v, want 2024-03-05", got, err)
+	}
+	if got, err := ExtractUploadDate(`<html></html>`, "2006-01-02"); err != nil || got != "" {
+		t.Errorf("ExtractUploadDate(missing) = %q, %v, want empty", got, err)
+	}
+	if _, err := ExtractUploadDate(`<div class="uploadate"><i></i> yesterday </div>`, "2006-01-02"); err == nil {
+		t.Error("ExtractUploadDate(unparsable) error = nil, want an error")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	in := "https://dood.la/d/a https://dood.watch/e/b https://doods.pro/f/c"
+	want := "https://doods.pro/d/a https://doods.pro/e/b https://doods.pro/f/c"
+	if got := Convert(in); got != want {
+		t.Errorf("Convert() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package dropbox contains functions that can be used to accurately extract and validate Dropbox shared links.
+package dropbox
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var dLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(www\.)?dropbox\.com/(s|sh|scl/fi|scl/fo)/[a-zA-Z0-9]+[^\s"'<>)\]]*`) // Extract Dropbox links
+var ogTitle *regexp.Regexp = regexp.MustCompile(`<meta property="og:title" content="(.*?)"`)                                        // Extract file/folder name
+var rBytes *regexp.Regexp = regexp.MustCompile(`"bytes":\s*(\d+)`)                                                                  // Extract size in bytes
+var rTotal *regexp.Regexp = regexp.MustCompile(`"total_num_files":\s*(\d+)`)                                                        // Extract folder item count
+var rFilename *regexp.Regexp = regexp.MustCompile(`"filename":\s*"`)                                                                // Identify folder items
+
+// Extract returns a slice of all Dropbox shared links contained within a string, if any. Each link is converted to
+// its canonical form via Clean().
+func Extract(res string) ([]string, error) {
+	var links []string
+	for _, v := range dLink.FindAllString(res, -1) {
+		links = append(links, Clean(html.UnescapeString(v)))
+	}
+	// Return all Dropbox links found within an http response
+	return links, nil
+}
+
+// Clean takes a Dropbox shared link and strips everything but the rlkey parameter, which is required to access links
+// of the /scl/ form. Download flags (dl=0/dl=1) and tracking parameters are removed.
+func Clean(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	rlkey := u.Query().Get("rlkey")
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.Scheme = "https"
+	u.Host = "www.dropbox.com"
+	if rlkey != "" {
+		u.RawQuery = "rlkey=" + url.QueryEscape(rlkey)
+	}
+	return u.String()
+}
+
+// ExtractType returns the link type (File or Folder) of a Dropbox shared link based on its path
+func ExtractType(link string) string {
+	if strings.Contains(link, "/sh/") || strings.Contains(link, "/scl/fo/") {
+		return "Folder"
+	}
+	return "File"
+}
+
+// ExtractTitle takes the body response/contents of a Dropbox share page (raw source/html (formatted as string)) as
+// an argument and returns the file/folder name as a string.
+func ExtractTitle(dropboxContents string) string {
+	if m := ogTitle.FindStringSubmatch(dropboxContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// ExtractSize takes the body response/contents of a Dropbox share page (raw source/html (formatted as string)) as
+// an argument and returns the shared file's size as a string.
+func ExtractSize(dropboxContents string) string {
+	m := rBytes.FindStringSubmatch(dropboxContents)
+	if m == nil {
+		return ""
+	}
+	b, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return ""
+	}
+	return handlers.FormatBytes(b)
+}
+
+// ExtractFileCount takes the body response/contents of a Dropbox folder page (raw source/html (formatted as string))
+// as an argument and returns the folder's item count as an integer. It will return -1 if the count is unavailable.
+func ExtractFileCount(dropboxContents string) int {
+	if m := rTotal.FindStringSubmatch(dropboxContents); m != nil {
+		if c, err := strconv.Atoi(m[1]); err == nil {
+			return c
+		}
+	}
+	if c := len(rFilename.FindAllStringIndex(dropboxContents, -1)); c > 0 {
+		return c
+	}
+	return -1
+}
+
+// Validate performs a GET request to the Dropbox URL and uses the response status code to identify its validity.
+// Deleted and expired shares respond with an error status. It returns the contents of the page alongside its validity.
+func Validate(x string) (bool, string, error) {
+	// Perform a GET request using the Dropbox URL
+	res, err := handlers.GetRes(x)
+	if err != nil {
+		return false, "", err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	if res.StatusCode == 200 {
+		return true, string(body), res.Body.Close()
+	} else {
+		return false, string(body), res.Body.Close()
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Dropbox links found within the response (if any) and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Dropbox links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on dropbox delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Dropbox links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Dropbox links
+		var results []models.Entry = nil
+		// Loop through each Dropbox link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on dropbox delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Dropbox", Title: ExtractTitle(contents), Type: ExtractType(v)}
+
+				if ent.Type == "Folder" {
+					ent.FileCount = ExtractFileCount(contents)
+				} else {
+					ent.Size = ExtractSize(contents)
+				}
+
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
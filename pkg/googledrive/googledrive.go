@@ -20,9 +20,16 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package googledrive
 
 import (
+	"encoding/json"
+	"html"
 	"io"
+	"mime"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf16"
 
 	"github.com/ax-i-om/tempest/internal/handlers"
 	"github.com/ax-i-om/tempest/pkg/models"
@@ -30,41 +37,246 @@ import (
 
 // Compile RegEx expressions for extraction of links/metadata
 
-// Extract Google Drive links
-var gLink *regexp.Regexp = regexp.MustCompile("(https|http)://drive.google.com/(folder|file|drive)/(d|folders)/(1[a-zA-Z0-9_-]{32}|0[a-zA-Z0-9_-]{27})")
-var roughTitle *regexp.Regexp = regexp.MustCompile(`<title>(.*?)</title>`) // Extract Title
+// Extract Google Drive links, including the /open?id= form and any trailing path/query (usp, resourcekey)
+var gLink *regexp.Regexp = regexp.MustCompile(`(https|http)://drive.google.com/(((folder|file|drive)/(d|folders))/|drive/u/\d+/folders/|open\?id=)(1[a-zA-Z0-9_-]{32}|0[a-zA-Z0-9_-]{27})[^\s"'<>)\]]*`)
 
-// Extract returns a slice of all Google Drive links contained within a string, if any.
+// Extract Google Docs/Sheets/Slides/Forms links
+var dLink *regexp.Regexp = regexp.MustCompile(`(https|http)://docs.google.com/(document|spreadsheets|presentation|forms)/d/(e/)?[a-zA-Z0-9_-]{25,}[^\s"'<>)\]]*`)
+
+var roughTitle *regexp.Regexp = regexp.MustCompile(`<title>(.*?)</title>`)                                             // Extract Title
+var gID *regexp.Regexp = regexp.MustCompile(`(1[a-zA-Z0-9_-]{32}|0[a-zA-Z0-9_-]{27})`)                                 // Extract Drive ID
+var dID *regexp.Regexp = regexp.MustCompile(`/(document|spreadsheets|presentation|forms)/d/((e/)?[a-zA-Z0-9_-]{25,})`) // Extract Docs kind and ID
+var roughIvd *regexp.Regexp = regexp.MustCompile(`window\['_DRIVE_ivd'\] = '(.*?)';`)                                  // Extract folder listing data
+var ucNameSize *regexp.Regexp = regexp.MustCompile(`class="uc-name-size"><a[^>]*>(.*?)</a> \((.*?)\)`)                 // Extract name and size from the download warning page
+var email *regexp.Regexp = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)                        // Extract owner email address
+var rKey *regexp.Regexp = regexp.MustCompile(`resourcekey=([a-zA-Z0-9_-]+)`)                                           // Extract resource key
+var filename *regexp.Regexp = regexp.MustCompile(`filename="(.*?)"`)                                                   // Extract file name from the Content-Disposition header
+
+// Indexes of the (undocumented) item arrays embedded in the _DRIVE_ivd folder listing data
+const (
+	ivdID    = 0
+	ivdName  = 2
+	ivdMime  = 3
+	ivdMtime = 9
+	ivdSize  = 13
+)
+
+// folderMime is the MIME type Google Drive assigns to folders
+const folderMime = "application/vnd.google-apps.folder"
+
+// docsTypes maps the path segment of a docs.google.com link to its link type
+var docsTypes = map[string]string{
+	"document":     "Document",
+	"spreadsheets": "Spreadsheet",
+	"presentation": "Presentation",
+	"forms":        "Form",
+}
+
+// docsMimes maps a Google Docs link type to the MIME type Google Drive assigns to it
+var docsMimes = map[string]string{
+	"Document":     "application/vnd.google-apps.document",
+	"Spreadsheet":  "application/vnd.google-apps.spreadsheet",
+	"Presentation": "application/vnd.google-apps.presentation",
+	"Form":         "application/vnd.google-apps.form",
+}
+
+// Extract returns a slice of all Google Drive and Google Docs links contained within a string, if any. Each link
+// is converted to its canonical form via Clean() and duplicates are removed.
 func Extract(res string) ([]string, error) {
-	// Return all Google Drive links found within an http response
-	return gLink.FindAllString(res, -1), nil
+	var links []string
+	seen := make(map[string]bool)
+	for _, v := range append(gLink.FindAllString(res, -1), dLink.FindAllString(res, -1)...) {
+		c := Clean(html.UnescapeString(v))
+		if !seen[c] {
+			seen[c] = true
+			links = append(links, c)
+		}
+	}
+	return links, nil
+}
+
+// Clean takes a Google Drive/Docs link and converts it to a canonical form. Tracking parameters such as usp=sharing
+// and trailing paths such as /view or /edit are stripped, while the resourcekey parameter (required to access
+// some older shares) is retained.
+func Clean(link string) string {
+	resourceKey := ExtractResourceKey(link)
+	var clean string
+	if m := dID.FindStringSubmatch(link); m != nil && strings.Contains(link, "docs.google.com") {
+		clean = "https://docs.google.com/" + m[1] + "/d/" + m[2]
+	} else {
+		id := ExtractID(link)
+		switch {
+		case strings.Contains(link, "/file/d/"):
+			clean = "https://drive.google.com/file/d/" + id
+		case strings.Contains(link, "/open?id="):
+			clean = "https://drive.google.com/open?id=" + id
+		default:
+			clean = "https://drive.google.com/drive/folders/" + id
+		}
+	}
+	if resourceKey != "" {
+		if strings.Contains(clean, "?") {
+			return clean + "&resourcekey=" + resourceKey
+		}
+		return clean + "?resourcekey=" + resourceKey
+	}
+	return clean
+}
+
+// ExtractID returns the Google Drive file/folder ID contained within a link
+func ExtractID(link string) string {
+	return gID.FindString(link)
+}
+
+// ExtractResourceKey returns the resourcekey parameter of a link, or an empty string if it has none
+func ExtractResourceKey(link string) string {
+	if m := rKey.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ExtractType returns the link type (File, Folder, Document, Spreadsheet, Presentation or Form) of a canonical link
+// produced by Clean(). Drive links of the /open?id= form are reported as Files until their contents are inspected.
+func ExtractType(link string) string {
+	if m := dID.FindStringSubmatch(link); m != nil && strings.Contains(link, "docs.google.com") {
+		return docsTypes[m[1]]
+	}
+	if strings.Contains(link, "/folders/") {
+		return "Folder"
+	}
+	return "File"
 }
 
 // ExtractTitle takes the body response/contents of a Google Drive page (raw source/html (formatted as string)) as
 // an argument and returns the title as a string.
 func ExtractTitle(googledriveContents string) string {
-	eTitle := roughTitle.FindString(googledriveContents)     // Extract rough title
-	eTitle = strings.ReplaceAll(eTitle, `<title>`, ``)       // Strip opening tags
-	eTitle = strings.ReplaceAll(eTitle, `</title>`, ``)      // Strip closing tags
-	return strings.ReplaceAll(eTitle, ` - Google Drive`, ``) // Strip extra text
+	eTitle := roughTitle.FindString(googledriveContents) // Extract rough title
+	eTitle = strings.ReplaceAll(eTitle, `<title>`, ``)   // Strip opening tags
+	eTitle = strings.ReplaceAll(eTitle, `</title>`, ``)  // Strip closing tags
+	// Strip extra text
+	for _, suffix := range []string{` - Google Drive`, ` - Google Docs`, ` - Google Sheets`, ` - Google Slides`, ` - Google Forms`} {
+		eTitle = strings.ReplaceAll(eTitle, suffix, ``)
+	}
+	return html.UnescapeString(eTitle)
 }
 
-// Validate performs a GET request to the Google Drive URL and uses the response status code to identify its validity
-func Validate(x string) (bool, error) {
+// ExtractFolder takes the body response/contents of a public Google Drive folder page (raw source/html (formatted as
+// string)) as an argument and returns the folder's contents, alongside an error. The listing is taken from the
+// _DRIVE_ivd data embedded within the page. Sizes are only available for non-Google (uploaded) files, and owners are
+// only available where the owner's email address is public.
+func ExtractFolder(googledriveContents string) ([]models.Child, error) {
+	m := roughIvd.FindStringSubmatch(googledriveContents)
+	if m == nil {
+		return nil, nil
+	}
+	decoded, err := unescapeJS(m[1])
+	if err != nil {
+		return nil, err
+	}
+	var ivd []json.RawMessage
+	if err := json.Unmarshal([]byte(decoded), &ivd); err != nil {
+		return nil, err
+	}
+	if len(ivd) == 0 {
+		return nil, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(ivd[0], &items); err != nil {
+		// A null listing represents an empty folder
+		return nil, nil
+	}
+
+	var children []models.Child
+	for _, raw := range items {
+		var item []interface{}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			continue
+		}
+		id, _ := at(item, ivdID).(string)
+		if id == "" {
+			continue
+		}
+		child := models.Child{}
+		child.Name, _ = at(item, ivdName).(string)
+		child.MimeType, _ = at(item, ivdMime).(string)
+		if child.MimeType == folderMime {
+			child.Type = "Folder"
+			child.Link = "https://drive.google.com/drive/folders/" + id
+		} else {
+			child.Type = "File"
+			child.Link = "https://drive.google.com/file/d/" + id
+		}
+		if s, ok := at(item, ivdSize).(float64); ok && s > 0 {
+			child.Size = handlers.FormatBytes(int64(s))
+		}
+		if t, ok := at(item, ivdMtime).(float64); ok && t > 0 {
+			child.Mtime = time.UnixMilli(int64(t)).UTC().Format(time.RFC3339)
+		}
+		child.Owner = email.FindString(string(raw))
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// ExtractFileInfo uses Google Drive's download endpoint to identify the name, MIME type and size of a shared file
+// without downloading it. It takes the file's ID and resource key (which may be empty) as arguments and returns the
+// name, MIME type, size (as a formatted string) and an error which will be nil if successful.
+func ExtractFileInfo(id, resourceKey string) (string, string, string, error) {
+	link := "https://drive.google.com/uc?id=" + id + "&export=download"
+	if resourceKey != "" {
+		link += "&resourcekey=" + resourceKey
+	}
+	res, err := handlers.GetRes(link)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer res.Body.Close()
+
+	contentType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if contentType != "text/html" {
+		// The file itself is being served, use the headers and leave the body unread
+		name := ""
+		if m := filename.FindStringSubmatch(res.Header.Get("Content-Disposition")); m != nil {
+			name = m[1]
+		}
+		return name, contentType, handlers.FormatBytes(res.ContentLength), nil
+	}
+
+	// Files too large to be virus scanned return a warning page containing the name and size
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", "", "", err
+	}
+	m := ucNameSize.FindStringSubmatch(string(body))
+	if m == nil {
+		return "", "", "", nil
+	}
+	name := html.UnescapeString(m[1])
+	return name, mime.TypeByExtension(path.Ext(name)), m[2] + "B", nil
+}
+
+// Validate performs a GET request to the Google Drive URL and uses the response status code to identify its validity.
+// Private Docs links redirect to a Google sign in page and are reported as invalid. Validate returns the contents of
+// the page alongside its validity.
+func Validate(x string) (bool, string, error) {
 	// Perform a GET request using the Google Drive URL
 	res, err := handlers.GetRes(x)
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
+	defer res.Body.Close()
 
-	if res.StatusCode == 200 {
-		return true, res.Body.Close()
-	} else {
-		return false, res.Body.Close()
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
 	}
+
+	return res.StatusCode == 200 && res.Request.URL.Host != "accounts.google.com", string(body), nil
 }
 
-// Delegate takes a string as an argument and returns a slice of valid Senvid links found within the response (if any) and an error
+// Delegate takes a string as an argument and returns a slice of valid Google Drive links found within the response (if any) and an error
 func Delegate(res, source string) ([]models.Entry, error) {
 	// Use Extract() to extract any existing Google Drive links from the response
 	x, err := Extract(res)
@@ -79,7 +291,7 @@ func Delegate(res, source string) ([]models.Entry, error) {
 		// Loop through each Google Drive link within the slice
 		for _, v := range x {
 			// Call the Validate function in order to check whether or not the link is valid
-			x, err := Validate(v)
+			x, contents, err := Validate(v)
 			if err != nil {
 				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
 				handlers.LogErr(err, "error occurred on googledrive delegate attempt to call validate")
@@ -87,32 +299,38 @@ func Delegate(res, source string) ([]models.Entry, error) {
 			}
 			// If x, the bool return by Validate(), is true: output the result to the terminal and append the link to the specified results slice.
 			if x {
-				// Get body contents of the sendvid link
-				res, err := handlers.GetRes(v)
-				if err != nil {
-					handlers.LogErr(err, "error occurred on googledrive delegate attempt to call getres")
-					continue
-				}
-
-				// Read results of the *http.Response body
-				body, err := io.ReadAll(res.Body)
-				if err != nil {
-					handlers.LogErr(err, "error occurred on googledrive delegate attempt to call readall")
-					continue
-				}
-
-				// Convert read results to a string
-				contents := string(body)
-
 				aTitle := ExtractTitle(contents)
+				aType := ExtractType(v)
+				// Links of the /open?id= form may point to folders, identify them by their embedded listing
+				if roughIvd.MatchString(contents) {
+					aType = "Folder"
+				}
 
 				// Create type Entry and specify the respective values
-				ent := models.Entry{Source: source, Link: v, Service: "Google Drive", Title: aTitle}
+				ent := models.Entry{Source: source, Link: v, Service: "Google Drive", Title: aTitle, Type: aType}
 
-				if strings.Contains(v, `/file/`) {
-					ent.Type = "File"
-				} else {
-					ent.Type = "Folder"
+				switch aType {
+				case "Folder":
+					children, err := ExtractFolder(contents)
+					if err != nil {
+						handlers.LogErr(err, "error occurred on googledrive delegate attempt to extract folder contents")
+					}
+					ent.MimeType = folderMime
+					ent.Children = children
+					ent.FileCount = len(children)
+				case "File":
+					name, mimeType, size, err := ExtractFileInfo(ExtractID(v), ExtractResourceKey(v))
+					if err != nil {
+						handlers.LogErr(err, "error occurred on googledrive delegate attempt to extract file metadata")
+					}
+					if ent.Title == "" {
+						ent.Title = name
+					}
+					ent.MimeType = mimeType
+					ent.Size = size
+				default:
+					// Docs, Sheets, Slides and Forms have no size and a fixed MIME type
+					ent.MimeType = docsMimes[aType]
 				}
 
 				// Append the entry to the results slice to be returned to the main runner
@@ -125,3 +343,58 @@ func Delegate(res, source string) ([]models.Entry, error) {
 	// Return nothing, if nothing happens (bruh)
 	return nil, nil
 }
+
+// at safely returns the value at index i of a decoded JSON array, or nil if it is out of range
+func at(item []interface{}, i int) interface{} {
+	if i < len(item) {
+		return item[i]
+	}
+	return nil
+}
+
+// unescapeJS converts the contents of a single quoted JavaScript string literal (using \xHH, \uHHHH and
+// backslash escapes) into a plain string.
+func unescapeJS(s string) (string, error) {
+	var b strings.Builder
+	var pending rune = -1 // high half of a UTF-16 surrogate pair
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'x', 'u':
+			n := 2
+			if s[i] == 'u' {
+				n = 4
+			}
+			if i+n >= len(s) {
+				return "", strconv.ErrSyntax
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", err
+			}
+			i += n
+			switch {
+			case utf16.IsSurrogate(rune(r)) && pending < 0:
+				pending = rune(r)
+			case pending >= 0:
+				b.WriteRune(utf16.DecodeRune(pending, rune(r)))
+				pending = -1
+			default:
+				b.WriteRune(rune(r))
+			}
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,194 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package krakenfiles contains functions that can be used to accurately extract and validate KrakenFiles links.
+package krakenfiles
+
+import (
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var kLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(www\.)?krakenfiles\.com/view/([a-zA-Z0-9]{10})/file\.html`)                         // Extract KrakenFiles links
+var ogTitle *regexp.Regexp = regexp.MustCompile(`<meta property="og:title" content="(.*?)"`)                                                       // Extract title
+var ogImage *regexp.Regexp = regexp.MustCompile(`<meta property="og:image" content="(.*?)"`)                                                       // Extract thumbnail
+var roughSize *regexp.Regexp = regexp.MustCompile(`File size</div>\s*<div class="lead-text">\s*(\d+(?:\.\d+)?\s*[KMGTP]?B)\s*</div>`)              // Extract size
+var roughViews *regexp.Regexp = regexp.MustCompile(`Views</div>\s*<div class="lead-text">\s*([\d,]+)\s*</div>`)                                    // Extract view count
+var roughDownloads *regexp.Regexp = regexp.MustCompile(`Downloads</div>\s*<div class="lead-text">\s*([\d,]+)\s*</div>`)                            // Extract download count
+var roughUploaded *regexp.Regexp = regexp.MustCompile(`Upload date</div>\s*<div class="lead-text">\s*(\d{2}-\d{2}-\d{4})`)                         // Extract upload date
+var rRemoved *regexp.Regexp = regexp.MustCompile(`(?i)(removed|deleted|blocked) (due to|because of|following) (a )?(dmca|copyright|abuse report)`) // Identify DMCA removal notices
+
+// Extract returns a slice of all KrakenFiles links contained within a string, if any.
+func Extract(res string) ([]string, error) {
+	// Return all KrakenFiles links found within an http response
+	return kLink.FindAllString(res, -1), nil
+}
+
+// ExtractTitle takes the body response/contents of a KrakenFiles page (raw source/html (formatted as string)) as
+// an argument and returns the file's title as a string.
+func ExtractTitle(krakenContents string) string {
+	if m := ogTitle.FindStringSubmatch(krakenContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// ExtractSize takes the body response/contents of a KrakenFiles page (raw source/html (formatted as string)) as
+// an argument and returns the file's size as a string.
+func ExtractSize(krakenContents string) string {
+	if m := roughSize.FindStringSubmatch(krakenContents); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ExtractThumbnail takes the body response/contents of a KrakenFiles page (raw source/html (formatted as string)) as
+// an argument. The extracted URL is unescaped to ensure validity and returned in string format.
+func ExtractThumbnail(krakenContents string) string {
+	if m := ogImage.FindStringSubmatch(krakenContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// ExtractViewCount takes the body response/contents of a KrakenFiles page (raw source/html (formatted as string)) as
+// an argument and returns the file's view count as an integer. If a failure occurs, -1 will be returned.
+func ExtractViewCount(krakenContents string) int {
+	return count(roughViews, krakenContents)
+}
+
+// ExtractDownloadCount takes the body response/contents of a KrakenFiles page (raw source/html (formatted as string))
+// as an argument and returns the file's download count as an integer. If a failure occurs, -1 will be returned.
+func ExtractDownloadCount(krakenContents string) int {
+	return count(roughDownloads, krakenContents)
+}
+
+// ExtractUploadDate takes the body response/contents of a KrakenFiles page (raw source/html (formatted as string))
+// and a dateFormat string as it's arguments. The dateFormat string will be used to format a time.Time into a more
+// favorable format. The default dateFormat used by tempest is "Jan 02, 2006". It will return a date in string format,
+// which has been formatted in accordance with the specified dateFormat, and an error which will be nil if successful.
+func ExtractUploadDate(krakenContents string, dateFormat string) (string, error) {
+	m := roughUploaded.FindStringSubmatch(krakenContents)
+	if m == nil {
+		return "", nil
+	}
+	parsed, err := time.Parse("02-01-2006", m[1]) // Convert string to *time.Time
+	if err != nil {
+		return "", err
+	}
+	return parsed.Format(dateFormat), nil // Format the date based on the format specified in dateFormat and return as string
+}
+
+// count extracts a comma separated number using the specified RegEx expression, returning -1 on failure
+func count(re *regexp.Regexp, krakenContents string) int {
+	m := re.FindStringSubmatch(krakenContents)
+	if m == nil {
+		return -1
+	}
+	c, err := strconv.Atoi(strings.ReplaceAll(m[1], ",", ""))
+	if err != nil {
+		return -1
+	}
+	return c
+}
+
+// Availability performs a GET request to the KrakenFiles URL and identifies whether the file is online, was removed
+// (e.g. following a DMCA notice) or never existed. It returns one of models.Online, models.Removed or
+// models.NotFound, the contents of the page and an error which will be nil if successful.
+func Availability(x string) (string, string, error) {
+	// Perform a GET request using the KrakenFiles URL
+	res, err := handlers.GetRes(x)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", "", err
+	}
+	contents := string(body)
+
+	// The removal notice is only looked for on error pages, as the title/description of a live file may contain it
+	switch {
+	case res.StatusCode == 451 || (res.StatusCode != 200 && rRemoved.MatchString(contents)):
+		return models.Removed, contents, res.Body.Close()
+	case res.StatusCode == 200:
+		return models.Online, contents, res.Body.Close()
+	default:
+		return models.NotFound, contents, res.Body.Close()
+	}
+}
+
+// Validate performs a GET request to the KrakenFiles URL and uses the response to identify its validity
+func Validate(x string) (bool, error) {
+	state, _, err := Availability(x)
+	return state == models.Online, err
+}
+
+// Delegate takes a string as an argument and returns a slice of valid KrakenFiles links found within the response (if any) and an error.
+// Links to removed content are returned too, without metadata and with Status set to models.Removed.
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing KrakenFiles links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on krakenfiles delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of KrakenFiles links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid KrakenFiles links
+		var results []models.Entry = nil
+		// Loop through each KrakenFiles link within the slice
+		for _, v := range x {
+			// Call the Availability function in order to check whether or not the link is valid
+			state, contents, err := Availability(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on krakenfiles delegate attempt to call availability")
+				continue
+			}
+			// If the file is online, extract its metadata and append the link to the specified results slice.
+			if state == models.Online {
+				aUploadDate, _ := ExtractUploadDate(contents, "Jan 02, 2006")
+
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "KrakenFiles", Status: models.Online, Title: ExtractTitle(contents), Size: ExtractSize(contents), Thumbnail: ExtractThumbnail(contents), Views: ExtractViewCount(contents), Downloads: ExtractDownloadCount(contents), Uploaded: aUploadDate, Type: "File"}
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			} else if state == models.Removed {
+				// Removed content is reported without metadata, so that takedowns can be told apart from invalid links
+				results = append(results, models.Entry{Source: source, Link: v, Service: "KrakenFiles", Type: "File", Status: models.Removed})
+			} else {
+				handlers.LogInfo("krakenfiles link " + v + " is offline: " + state)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,64 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package krakenfiles
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestAvailability(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/online":
+			w.Write([]byte(`<html><title>file</title></html>`))
+		case "/online-notice":
+			w.Write([]byte(`<html><title>Mirror of the file deleted due to a DMCA notice</title></html>`))
+		case "/removed":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<p>This file was removed due to a copyright notice.</p>`))
+		case "/legal":
+			w.WriteHeader(http.StatusUnavailableForLegalReasons)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/online", models.Online},
+		{"/online-notice", models.Online},
+		{"/removed", models.Removed},
+		{"/legal", models.Removed},
+		{"/missing", models.NotFound},
+	}
+	for _, tt := range tests {
+		got, _, err := Availability(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Availability(%q) error = %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("Availability(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package mediafire contains functions that can be used to accurately extract and validate MediaFire links.
+package mediafire
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var mLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(www\.)?mediafire.com/(file|folder)/([a-zA-Z0-9]{11,15})`) // Extract MediaFire links
+
+// MaxChunks is the maximum number of chunks (of up to 100 items each) requested when enumerating a folder's contents
+var MaxChunks = 10
+
+// apiBase is the base URL of MediaFire's public API
+const apiBase = "https://www.mediafire.com/api/1.5/"
+
+// Extract returns a slice of all MediaFire links contained within a string, if any. Trailing file names are stripped,
+// leaving links of the form https://www.mediafire.com/(file|folder)/<key>
+func Extract(res string) ([]string, error) {
+	var links []string
+	for _, m := range mLink.FindAllStringSubmatch(res, -1) {
+		links = append(links, "https://www.mediafire.com/"+m[3]+"/"+m[4])
+	}
+	// Return all MediaFire links found within an http response
+	return links, nil
+}
+
+// ExtractKey returns the kind (file or folder) and the quick key/folder key of a MediaFire link
+func ExtractKey(link string) (string, string) {
+	m := mLink.FindStringSubmatch(link)
+	if m == nil {
+		return "", ""
+	}
+	return m[3], m[4]
+}
+
+// ExtractFileInfo takes the contents of the body response from the MediaFire file/get_info endpoint and unmarshals
+// it, returning the metadata as type *models.MFFile alongside an error
+func ExtractFileInfo(mediafireContents string) (*models.MFFile, error) {
+	info := new(struct {
+		Response struct {
+			FileInfo models.MFFile `json:"file_info"`
+		} `json:"response"`
+	})
+	err := json.Unmarshal([]byte(mediafireContents), &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info.Response.FileInfo, nil
+}
+
+// ExtractFolderInfo takes the contents of the body response from the MediaFire folder/get_info endpoint and unmarshals
+// it, returning the metadata as type *models.MFFolder alongside an error
+func ExtractFolderInfo(mediafireContents string) (*models.MFFolder, error) {
+	info := new(struct {
+		Response struct {
+			FolderInfo models.MFFolder `json:"folder_info"`
+		} `json:"response"`
+	})
+	err := json.Unmarshal([]byte(mediafireContents), &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info.Response.FolderInfo, nil
+}
+
+// ExtractFolderContents uses the MediaFire folder/get_content endpoint to enumerate the files and subfolders
+// contained within a public folder. It takes a folder key as an argument and returns the contents as a slice of
+// child records, alongside an error which will be nil if successful. At most MaxChunks chunks of each content type
+// are requested.
+func ExtractFolderContents(folderKey string) ([]models.Child, error) {
+	var children []models.Child
+	for _, contentType := range []string{"folders", "files"} {
+		for chunk := 1; chunk <= MaxChunks; chunk++ {
+			res, err := handlers.GetRes(apiBase + "folder/get_content.php?response_format=json&folder_key=" + folderKey + "&content_type=" + contentType + "&chunk=" + fmt.Sprint(chunk))
+			if err != nil {
+				return children, err
+			}
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				return children, err
+			}
+
+			content := new(struct {
+				Response struct {
+					FolderContent struct {
+						MoreChunks string            `json:"more_chunks"`
+						Files      []models.MFFile   `json:"files"`
+						Folders    []models.MFFolder `json:"folders"`
+					} `json:"folder_content"`
+				} `json:"response"`
+			})
+			err = json.Unmarshal(body, &content)
+			if err != nil {
+				return children, err
+			}
+
+			fc := content.Response.FolderContent
+			for _, f := range fc.Folders {
+				children = append(children, models.Child{Name: f.Name, Link: "https://www.mediafire.com/folder/" + f.FolderKey, Type: "Folder", Mtime: f.Created})
+			}
+			for _, f := range fc.Files {
+				children = append(children, models.Child{Name: f.Filename, Link: "https://www.mediafire.com/file/" + f.QuickKey, Type: "File", MimeType: f.MimeType, Size: handlers.FormatBytes(f.Size), Mtime: f.Created, Hash: f.Hash})
+			}
+			if fc.MoreChunks != "yes" {
+				break
+			}
+		}
+	}
+	return children, nil
+}
+
+// FormatDate converts a date returned by the MediaFire API into the specified dateFormat. The default dateFormat
+// used by tempest is "Jan 02, 2006". An empty string is returned if the date could not be parsed.
+func FormatDate(date, dateFormat string) string {
+	parsed, err := time.Parse("2006-01-02 15:04:05", date)
+	if err != nil {
+		return ""
+	}
+	return parsed.Format(dateFormat)
+}
+
+// Validate takes a MediaFire link/URL and queries the matching get_info endpoint to identify whether or not the link is
+// valid/online. It returns the contents of the API response alongside its validity.
+func Validate(x string) (bool, string, error) {
+	kind, key := ExtractKey(x)
+	link := apiBase + "file/get_info.php?response_format=json&quick_key=" + key
+	if kind == "folder" {
+		link = apiBase + "folder/get_info.php?response_format=json&folder_key=" + key
+	}
+
+	// Perform a GET request using the MediaFire API
+	res, err := handlers.GetRes(link)
+	if err != nil {
+		return false, "", err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	result := new(struct {
+		Response struct {
+			Result string `json:"result"`
+		} `json:"response"`
+	})
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, string(body), res.Body.Close()
+	}
+
+	// The API responds with a result of "Error" for invalid/removed keys
+	if result.Response.Result == "Success" {
+		return true, string(body), res.Body.Close()
+	} else {
+		return false, string(body), res.Body.Close()
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid MediaFire links found within the response (if any) and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing MediaFire links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on mediafire delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of MediaFire links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid MediaFire links
+		var results []models.Entry = nil
+		// Loop through each MediaFire link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on mediafire delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "MediaFire"}
+
+				kind, key := ExtractKey(v)
+				if kind == "folder" {
+					info, err := ExtractFolderInfo(contents)
+					if err != nil {
+						handlers.LogErr(err, "error occurred on mediafire delegate attempt to extract folder metadata")
+						continue
+					}
+					children, err := ExtractFolderContents(key)
+					if err != nil {
+						handlers.LogErr(err, "error occurred on mediafire delegate attempt to enumerate folder contents")
+					}
+					ent.Title = info.Name
+					ent.Description = info.Description
+					ent.Type = "Folder"
+					ent.FileCount = info.FileCount
+					ent.Owner = info.OwnerName
+					ent.Uploaded = FormatDate(info.Created, "Jan 02, 2006")
+					ent.Children = children
+				} else {
+					info, err := ExtractFileInfo(contents)
+					if err != nil {
+						handlers.LogErr(err, "error occurred on mediafire delegate attempt to extract file metadata")
+						continue
+					}
+					ent.Title = info.Filename
+					ent.Description = info.Description
+					ent.Type = "File"
+					ent.MimeType = info.MimeType
+					ent.Size = handlers.FormatBytes(info.Size)
+					ent.Downloads = info.Downloads
+					ent.Owner = info.OwnerName
+					ent.Uploaded = FormatDate(info.Created, "Jan 02, 2006")
+					ent.Hash = info.Hash
+				}
+
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
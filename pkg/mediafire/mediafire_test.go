@@ -0,0 +1,90 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package mediafire
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestExtract(t *testing.T) {
+	in := `<a href="http://mediafire.com/file/abc123def456g/clip.zip/file">file</a>
+		https://www.mediafire.com/folder/fold3rkey12ab/Album https://www.mediafire.com/view/abc123def456g`
+	want := []string{"https://www.mediafire.com/file/abc123def456g", "https://www.mediafire.com/folder/fold3rkey12ab"}
+	got, err := Extract(in)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractKey(t *testing.T) {
+	tests := []struct {
+		link, kind, key string
+	}{
+		{"https://www.mediafire.com/file/abc123def456g", "file", "abc123def456g"},
+		{"https://www.mediafire.com/folder/fold3rkey12ab", "folder", "fold3rkey12ab"},
+		{"https://example.com/file/abc123def456g", "", ""},
+	}
+	for _, tt := range tests {
+		if kind, key := ExtractKey(tt.link); kind != tt.kind || key != tt.key {
+			t.Errorf("ExtractKey(%q) = %q, %q, want %q, %q", tt.link, kind, key, tt.kind, tt.key)
+		}
+	}
+}
+
+func TestExtractFileInfo(t *testing.T) {
+	info, err := ExtractFileInfo(`{"response":{"action":"file/get_info","result":"Success","file_info":{"quickkey":"abc123def456g",
+		"filename":"clip.zip","description":"a clip","created":"2024-03-05 10:20:30","downloads":"42","size":"1048576",
+		"mimetype":"application/zip","hash":"deadbeef","owner_name":"someone"}}}`)
+	if err != nil {
+		t.Fatalf("ExtractFileInfo() error = %v", err)
+	}
+	want := models.MFFile{QuickKey: "abc123def456g", Filename: "clip.zip", Description: "a clip", Created: "2024-03-05 10:20:30", Downloads: 42, Size: 1048576, MimeType: "application/zip", Hash: "deadbeef", OwnerName: "someone"}
+	if *info != want {
+		t.Errorf("ExtractFileInfo() = %+v, want %+v", *info, want)
+	}
+	if _, err := ExtractFileInfo(`<html>`); err == nil {
+		t.Error("ExtractFileInfo(html) error = nil, want an error")
+	}
+}
+
+func TestExtractFolderInfo(t *testing.T) {
+	info, err := ExtractFolderInfo(`{"response":{"action":"folder/get_info","result":"Success","folder_info":{"folderkey":"fold3rkey12ab",
+		"name":"Album","description":"","created":"2024-03-05 10:20:30","file_count":"7","owner_name":"someone"}}}`)
+	if err != nil {
+		t.Fatalf("ExtractFolderInfo() error = %v", err)
+	}
+	want := models.MFFolder{FolderKey: "fold3rkey12ab", Name: "Album", Created: "2024-03-05 10:20:30", FileCount: 7, OwnerName: "someone"}
+	if *info != want {
+		t.Errorf("ExtractFolderInfo() = %+v, want %+v", *info, want)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	if got := FormatDate("2024-03-05 10:20:30", "Jan 02, 2006"); got != "Mar 05, 2024" {
+		t.Errorf("FormatDate() = %q, want Mar 05, 2024", got)
+	}
+	if got := FormatDate("2024-03-05T10:20:30Z", "Jan 02, 2006"); got != "" {
+		t.Errorf("FormatDate(invalid) = %q, want empty", got)
+	}
+}
@@ -27,6 +27,7 @@ import (
 type Entry struct {
 	Source string `json:"source"`
 	Link   string `json:"link"`
+	Seen   string `json:"seen"`
 
 	Title       string `json:"title"`
 	Description string `json:"description"`
@@ -34,17 +35,53 @@ type Entry struct {
 
 	Uploaded string `json:"uploaded"`
 	Mtime    string `json:"mtime"`
+	Duration string `json:"duration"`
 
 	Type      string `json:"type"`
+	MimeType  string `json:"mimetype"`
 	Size      string `json:"size"`
 	FileCount int    `json:"filecount"`
+	Owner     string `json:"owner"`
+
+	Resolution string `json:"resolution"`
+	Direct     string `json:"direct"`
 
 	Thumbnail string `json:"thumbnail"`
 	Downloads int    `json:"downloads"`
 	Views     int    `json:"views"`
+	Members   int    `json:"members"`
 
 	Hash    string `json:"hash"`
+	AltHash string `json:"althash"`
 	Malware string `json:"malware"`
+
+	Trackers []string `json:"trackers"`
+	Children []Child  `json:"children"`
+
+	Obfuscation string   `json:"obfuscation"`
+	Redirects   []string `json:"redirects"`
+
+	Status string `json:"status"` // Availability state (Online or Removed), empty if the provider cannot tell
+}
+
+// Availability states reported by providers that are able to tell removed content apart from invalid links
+const (
+	Online   = "Online"    // The link is valid and its content is available
+	Removed  = "Removed"   // The content was taken down, e.g. following a DMCA notice
+	NotFound = "Not Found" // The link never existed or its ID is invalid
+)
+
+// Child represents a single file/folder contained within a folder Entry.
+type Child struct {
+	Name     string `json:"name"`
+	Link     string `json:"link"`
+	Type     string `json:"type"`
+	MimeType string `json:"mimetype"`
+	Size     string `json:"size"`
+	Owner    string `json:"owner"`
+	Mtime    string `json:"mtime"`
+	Hash     string `json:"hash"`
+	Malware  string `json:"malware"`
 }
 
 // CMRInfo represents the extracted metadata from cloud.mail.ru files/folders
@@ -65,6 +102,101 @@ type CMRInfo struct {
 		ID    string `json:"id"`
 		Ctime int    `json:"ctime"`
 	} `json:"public"`
+	Count struct {
+		Folders int `json:"folders"`
+		Files   int `json:"files"`
+	} `json:"count"`
+	List []CMRInfo `json:"list"`
+}
+
+// PDInfo represents the metadata returned by the Pixeldrain file info endpoint
+type PDInfo struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	Views      int    `json:"views"`
+	Downloads  int    `json:"downloads"`
+	DateUpload string `json:"date_upload"`
+	MimeType   string `json:"mime_type"`
+	HashSHA256 string `json:"hash_sha256"`
+}
+
+// PDList represents the metadata returned by the Pixeldrain list endpoint
+type PDList struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	DateCreated string   `json:"date_created"`
+	FileCount   int      `json:"file_count"`
+	Files       []PDInfo `json:"files"`
+}
+
+// MFFile represents the metadata returned by the MediaFire API for a file. Numeric values are returned as strings.
+type MFFile struct {
+	QuickKey    string `json:"quickkey"`
+	Filename    string `json:"filename"`
+	Description string `json:"description"`
+	Created     string `json:"created"`
+	Downloads   int    `json:"downloads,string"`
+	Size        int64  `json:"size,string"`
+	MimeType    string `json:"mimetype"`
+	Hash        string `json:"hash"`
+	OwnerName   string `json:"owner_name"`
+}
+
+// MFFolder represents the metadata returned by the MediaFire API for a folder. Numeric values are returned as strings.
+type MFFolder struct {
+	FolderKey   string `json:"folderkey"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Created     string `json:"created"`
+	FileCount   int    `json:"file_count,string"`
+	OwnerName   string `json:"owner_name"`
+}
+
+// YDResource represents the metadata returned by the Yandex Disk public resources endpoint
+type YDResource struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Type      string `json:"type"`
+	MimeType  string `json:"mime_type"`
+	Size      int64  `json:"size"`
+	Created   string `json:"created"`
+	Modified  string `json:"modified"`
+	SHA256    string `json:"sha256"`
+	PublicURL string `json:"public_url"` // Only set for published resources
+	Embedded  struct {
+		Items []YDResource `json:"items"`
+		Total int          `json:"total"`
+	} `json:"_embedded"`
+}
+
+// TBShare represents the metadata returned by the Terabox shorturlinfo endpoint
+type TBShare struct {
+	Errno int    `json:"errno"`
+	Title string `json:"title"`
+	Ctime int64  `json:"ctime"`
+	List  []struct {
+		ServerFilename string `json:"server_filename"`
+		Size           int64  `json:"size"`
+		IsDir          int    `json:"isdir"`
+		ServerCtime    int64  `json:"server_ctime"`
+		MD5            string `json:"md5"`
+	} `json:"list"`
+}
+
+// DCInvite represents the metadata returned by the Discord invite endpoint
+type DCInvite struct {
+	Code  string `json:"code"`
+	Guild struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Icon        string `json:"icon"`
+	} `json:"guild"`
+	Channel struct {
+		Name string `json:"name"`
+	} `json:"channel"`
+	ApproximateMemberCount int `json:"approximate_member_count"`
 }
 
 // WaitGroupCount represents a countable sync.WaitGroup
@@ -0,0 +1,195 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package onedrive contains functions that can be used to accurately extract and validate OneDrive shared links.
+package onedrive
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+
+// Extract OneDrive short links (1drv.ms) and long links (onedrive.live.com)
+var oLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(1drv\.ms/[a-z]/(s![a-zA-Z0-9_-]+|c/[a-zA-Z0-9]+/[a-zA-Z0-9_-]+)|onedrive\.live\.com/(redir|embed|download)?\?[^\s"'<>)\]]*(resid|id)=[^\s"'<>)\]]+)`)
+var ogTitle *regexp.Regexp = regexp.MustCompile(`<meta property="og:title" content="(.*?)"`) // Extract file/folder name
+var rSize *regexp.Regexp = regexp.MustCompile(`"size":\s*(\d+)`)                             // Extract size in bytes
+var rChildCount *regexp.Regexp = regexp.MustCompile(`"childCount":\s*(\d+)`)                 // Extract folder item count
+var rShortType *regexp.Regexp = regexp.MustCompile(`1drv\.ms/([a-z])/`)                      // Extract the item type letter of a short link
+
+// Extract returns a slice of all OneDrive shared links contained within a string, if any.
+func Extract(res string) ([]string, error) {
+	var links []string
+	for _, v := range oLink.FindAllString(res, -1) {
+		links = append(links, html.UnescapeString(v))
+	}
+	// Return all OneDrive links found within an http response
+	return links, nil
+}
+
+// ExtractType returns the link type (File or Folder) of a OneDrive link. Short links carry the item type as a single
+// letter (f for folders, u/w/x/p/b/v/i/t for files), long links are identified by the resolved URL.
+func ExtractType(link string) string {
+	if m := rShortType.FindStringSubmatch(link); m != nil {
+		if m[1] == "f" {
+			return "Folder"
+		}
+		return "File"
+	}
+	if strings.Contains(strings.ToLower(link), "folder") {
+		return "Folder"
+	}
+	return "File"
+}
+
+// ExtractTitle takes the body response/contents of a OneDrive share page (raw source/html (formatted as string)) as
+// an argument and returns the file/folder name as a string.
+func ExtractTitle(onedriveContents string) string {
+	if m := ogTitle.FindStringSubmatch(onedriveContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// ExtractSize takes the body response/contents of a OneDrive share page (raw source/html (formatted as string)) as
+// an argument and returns the shared item's size as a string.
+func ExtractSize(onedriveContents string) string {
+	m := rSize.FindStringSubmatch(onedriveContents)
+	if m == nil {
+		return ""
+	}
+	b, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return ""
+	}
+	return handlers.FormatBytes(b)
+}
+
+// ExtractFileCount takes the body response/contents of a OneDrive folder page (raw source/html (formatted as string))
+// as an argument and returns the folder's item count as an integer. It will return -1 if the count is unavailable.
+func ExtractFileCount(onedriveContents string) int {
+	m := rChildCount.FindStringSubmatch(onedriveContents)
+	if m == nil {
+		return -1
+	}
+	c, err := strconv.Atoi(m[1])
+	if err != nil {
+		return -1
+	}
+	return c
+}
+
+// unavailable reports whether the URL a OneDrive link resolved to is the Microsoft sign in page or an error page. Only
+// the host, the path segments and the query keys are inspected, so item names containing "error" are not affected.
+func unavailable(u *url.URL) bool {
+	switch strings.ToLower(u.Hostname()) {
+	case "login.live.com", "login.microsoftonline.com":
+		return true
+	}
+	for _, seg := range strings.Split(strings.ToLower(u.Path), "/") {
+		if seg == "error" || seg == "error.aspx" {
+			return true
+		}
+	}
+	for k := range u.Query() {
+		if strings.EqualFold(k, "errorcode") {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate performs a GET request to the OneDrive URL, following any redirects (short links redirect to
+// onedrive.live.com), and uses the response status code to identify its validity. It returns the contents of the
+// page and the resolved URL alongside its validity.
+func Validate(x string) (bool, string, string, error) {
+	// Perform a GET request using the OneDrive URL, the client follows redirects automatically
+	res, err := handlers.GetRes(x)
+	if err != nil {
+		return false, "", "", err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", "", err
+	}
+
+	resolved := res.Request.URL.String()
+
+	// Removed shares redirect to an error page or the Microsoft sign in page
+	if res.StatusCode == 200 && !unavailable(res.Request.URL) {
+		return true, string(body), resolved, res.Body.Close()
+	} else {
+		return false, string(body), resolved, res.Body.Close()
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid OneDrive links found within the response (if any) and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing OneDrive links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on onedrive delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of OneDrive links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid OneDrive links
+		var results []models.Entry = nil
+		// Loop through each OneDrive link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, resolved, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on onedrive delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "OneDrive", Title: ExtractTitle(contents), Type: ExtractType(v)}
+				if !strings.Contains(v, "1drv.ms") {
+					ent.Type = ExtractType(resolved)
+				}
+
+				if ent.Type == "Folder" {
+					ent.FileCount = ExtractFileCount(contents)
+				} else {
+					ent.Size = ExtractSize(contents)
+				}
+
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,136 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package onedrive
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	in := `<a href="https://1drv.ms/u/s!AbCd_12-x">file</a> <a href="https://1drv.ms/f/s!FoLd3r">folder</a>
+		https://onedrive.live.com/redir?cid=ABC&amp;resid=ABC%21101&amp;authkey=xyz) https://example.com/1drv.ms/u/`
+	want := []string{
+		"https://1drv.ms/u/s!AbCd_12-x",
+		"https://1drv.ms/f/s!FoLd3r",
+		"https://onedrive.live.com/redir?cid=ABC&resid=ABC%21101&authkey=xyz",
+	}
+	got, err := Extract(in)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	page := `<meta property="og:title" content="Tom &amp; Jerry.zip"><script>{"size": 1048576, "childCount": 12}</script>`
+	if got := ExtractTitle(page); got != "Tom & Jerry.zip" {
+		t.Errorf("ExtractTitle() = %q", got)
+	}
+	if got, want := ExtractSize(page), ExtractSize(`"size":1048576`); got == "" || got != want {
+		t.Errorf("ExtractSize() = %q, want %q", got, want)
+	}
+	if got := ExtractSize(`<html></html>`); got != "" {
+		t.Errorf("ExtractSize(empty) = %q, want empty", got)
+	}
+	if got := ExtractFileCount(page); got != 12 {
+		t.Errorf("ExtractFileCount() = %d, want 12", got)
+	}
+	if got := ExtractFileCount(`<html></html>`); got != -1 {
+		t.Errorf("ExtractFileCount(empty) = %d, want -1", got)
+	}
+
+	types := map[string]string{
+		"https://1drv.ms/f/s!abc":                              "Folder",
+		"https://1drv.ms/u/s!abc":                              "File",
+		"https://onedrive.live.com/?id=root&folder=1":          "Folder",
+		"https://onedrive.live.com/redir?resid=ABC&authkey=xy": "File",
+	}
+	for link, want := range types {
+		if got := ExtractType(link); got != want {
+			t.Errorf("ExtractType(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://onedrive.live.com/?id=root&resid=ABC", false},
+		{"https://onedrive.live.com/download/error-log.zip", false},
+		{"https://onedrive.live.com/?id=root&name=errors", false},
+		{"https://login.live.com/login.srf?wa=wsignin1.0", true},
+		{"https://onedrive.live.com/error", true},
+		{"https://contoso.sharepoint.com/_layouts/15/error.aspx", true},
+		{"https://onedrive.live.com/?errorcode=ItemNotFound", true},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := unavailable(u); got != tt.want {
+			t.Errorf("unavailable(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/u/s!valid":
+			http.Redirect(w, r, "/files/error-log.zip", http.StatusFound)
+		case "/u/s!gone":
+			http.Redirect(w, r, "/error?errorcode=ItemNotFound", http.StatusFound)
+		case "/files/error-log.zip", "/error":
+			w.Write([]byte(`<meta property="og:title" content="page">`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		want     bool
+		resolved string
+	}{
+		{"/u/s!valid", true, "/files/error-log.zip"},
+		{"/u/s!gone", false, "/error?errorcode=ItemNotFound"},
+		{"/u/s!missing", false, "/u/s!missing"},
+	}
+	for _, tt := range tests {
+		got, _, resolved, err := Validate(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Validate(%q) error = %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("Validate(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+		if resolved != srv.URL+tt.resolved {
+			t.Errorf("Validate(%q) resolved = %q, want %q", tt.path, resolved, srv.URL+tt.resolved)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package pixeldrain contains functions that can be used to accurately extract and validate Pixeldrain links.
+package pixeldrain
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var pLink *regexp.Regexp = regexp.MustCompile(`(https|http)://pixeldrain.com/(u|l)/([a-zA-Z0-9]{8})`) // Extract Pixeldrain links
+
+// Extract returns a slice of all Pixeldrain links contained within a string, if any.
+func Extract(res string) ([]string, error) {
+	// Return all Pixeldrain links found within an http response
+	return pLink.FindAllString(res, -1), nil
+}
+
+// APILink converts a Pixeldrain file (/u/) or list (/l/) link into the public API endpoint containing its metadata.
+func APILink(link string) string {
+	m := pLink.FindStringSubmatch(link)
+	if m == nil {
+		return ""
+	}
+	if m[2] == "l" {
+		return "https://pixeldrain.com/api/list/" + m[3]
+	}
+	return "https://pixeldrain.com/api/file/" + m[3] + "/info"
+}
+
+// ExtractFileInfo takes the contents of the body response from the Pixeldrain file info endpoint and unmarshals
+// it, returning the metadata as type *models.PDInfo alongside an error
+func ExtractFileInfo(pixeldrainContents string) (*models.PDInfo, error) {
+	info := new(models.PDInfo)
+	err := json.Unmarshal([]byte(pixeldrainContents), &info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ExtractListInfo takes the contents of the body response from the Pixeldrain list endpoint and unmarshals
+// it, returning the metadata as type *models.PDList alongside an error
+func ExtractListInfo(pixeldrainContents string) (*models.PDList, error) {
+	list := new(models.PDList)
+	err := json.Unmarshal([]byte(pixeldrainContents), &list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// FormatDate converts a date returned by the Pixeldrain API into the specified dateFormat. The default dateFormat
+// used by tempest is "Jan 02, 2006". An empty string is returned if the date could not be parsed.
+func FormatDate(date, dateFormat string) string {
+	parsed, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return ""
+	}
+	return parsed.Format(dateFormat)
+}
+
+// Validate takes a Pixeldrain link/URL and queries the public API to identify whether or not the link is valid/online.
+// It returns the contents of the API response alongside its validity.
+func Validate(x string) (bool, string, error) {
+	// Perform a GET request using the Pixeldrain API
+	res, err := handlers.GetRes(APILink(x))
+	if err != nil {
+		return false, "", err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	// The API responds with a 404 and {"success":false,"value":"not_found"} for invalid links
+	if res.StatusCode == 200 {
+		return true, string(body), res.Body.Close()
+	} else {
+		return false, string(body), res.Body.Close()
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Pixeldrain links found within the response (if any) and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Pixeldrain links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on pixeldrain delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Pixeldrain links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Pixeldrain links
+		var results []models.Entry = nil
+		// Loop through each Pixeldrain link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on pixeldrain delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Pixeldrain"}
+
+				if pLink.FindStringSubmatch(v)[2] == "l" {
+					list, err := ExtractListInfo(contents)
+					if err != nil {
+						handlers.LogErr(err, "error occurred on pixeldrain delegate attempt to extract list metadata")
+						continue
+					}
+					var total int64
+					for _, f := range list.Files {
+						total += f.Size
+						ent.Children = append(ent.Children, models.Child{Name: f.Name, Link: "https://pixeldrain.com/u/" + f.ID, Type: "File", MimeType: f.MimeType, Size: handlers.FormatBytes(f.Size), Mtime: f.DateUpload, Hash: f.HashSHA256})
+					}
+					ent.Title = list.Title
+					ent.Type = "Folder"
+					ent.FileCount = list.FileCount
+					ent.Size = handlers.FormatBytes(total)
+					ent.Uploaded = FormatDate(list.DateCreated, "Jan 02, 2006")
+				} else {
+					info, err := ExtractFileInfo(contents)
+					if err != nil {
+						handlers.LogErr(err, "error occurred on pixeldrain delegate attempt to extract file metadata")
+						continue
+					}
+					ent.Title = info.Name
+					ent.Type = "File"
+					ent.MimeType = info.MimeType
+					ent.Size = handlers.FormatBytes(info.Size)
+					ent.Views = info.Views
+					ent.Downloads = info.Downloads
+					ent.Uploaded = FormatDate(info.DateUpload, "Jan 02, 2006")
+					ent.Thumbnail = "https://pixeldrain.com/api/file/" + info.ID + "/thumbnail"
+					ent.Hash = info.HashSHA256
+				}
+
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,85 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package pixeldrain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestExtract(t *testing.T) {
+	in := `<a href="https://pixeldrain.com/u/AbCd1234">file</a> https://pixeldrain.com/l/ZyXw9876 https://pixeldrain.com/x/AbCd1234`
+	want := []string{"https://pixeldrain.com/u/AbCd1234", "https://pixeldrain.com/l/ZyXw9876"}
+	got, err := Extract(in)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestAPILink(t *testing.T) {
+	tests := map[string]string{
+		"https://pixeldrain.com/u/AbCd1234": "https://pixeldrain.com/api/file/AbCd1234/info",
+		"https://pixeldrain.com/l/ZyXw9876": "https://pixeldrain.com/api/list/ZyXw9876",
+		"https://example.com/u/AbCd1234":    "",
+	}
+	for link, want := range tests {
+		if got := APILink(link); got != want {
+			t.Errorf("APILink(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestExtractFileInfo(t *testing.T) {
+	info, err := ExtractFileInfo(`{"success":true,"id":"AbCd1234","name":"clip.mp4","size":1048576,"views":12,"downloads":3,
+		"date_upload":"2024-03-05T10:20:30.123Z","mime_type":"video/mp4","hash_sha256":"deadbeef"}`)
+	if err != nil {
+		t.Fatalf("ExtractFileInfo() error = %v", err)
+	}
+	want := models.PDInfo{ID: "AbCd1234", Name: "clip.mp4", Size: 1048576, Views: 12, Downloads: 3, DateUpload: "2024-03-05T10:20:30.123Z", MimeType: "video/mp4", HashSHA256: "deadbeef"}
+	if *info != want {
+		t.Errorf("ExtractFileInfo() = %+v, want %+v", *info, want)
+	}
+	if _, err := ExtractFileInfo(`<html>`); err == nil {
+		t.Error("ExtractFileInfo(html) error = nil, want an error")
+	}
+}
+
+func TestExtractListInfo(t *testing.T) {
+	list, err := ExtractListInfo(`{"id":"ZyXw9876","title":"Album","date_created":"2024-03-05T10:20:30Z","file_count":2,
+		"files":[{"id":"a1","name":"one.jpg","size":10},{"id":"b2","name":"two.jpg","size":20}]}`)
+	if err != nil {
+		t.Fatalf("ExtractListInfo() error = %v", err)
+	}
+	if list.Title != "Album" || list.FileCount != 2 || len(list.Files) != 2 || list.Files[1].ID != "b2" || list.Files[1].Size != 20 {
+		t.Errorf("ExtractListInfo() = %+v", *list)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	if got := FormatDate("2024-03-05T10:20:30.123Z", "Jan 02, 2006"); got != "Mar 05, 2024" {
+		t.Errorf("FormatDate() = %q, want Mar 05, 2024", got)
+	}
+	if got := FormatDate("yesterday", "Jan 02, 2006"); got != "" {
+		t.Errorf("FormatDate(invalid) = %q, want empty", got)
+	}
+}
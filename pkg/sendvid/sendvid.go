@@ -25,6 +25,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ax-i-om/tempest/internal/handlers"
 	"github.com/ax-i-om/tempest/pkg/models"
@@ -35,6 +36,13 @@ var sLink *regexp.Regexp = regexp.MustCompile("(https|http)://sendvid.com/([a-z0
 var rThumb *regexp.Regexp = regexp.MustCompile(`(https|http)://thumbs(.*?).jpg`)                                    // Extract thumbnail
 var roughViews *regexp.Regexp = regexp.MustCompile(`<p class="hits"><i class="icon-icn-view"></i>([0-9](.*?))</p>`) // Extract view count
 var roughTitle *regexp.Regexp = regexp.MustCompile(`<title>(.*?)</title>`)                                          // Extract title
+var rSource *regexp.Regexp = regexp.MustCompile(`<source src="(.*?)"`)                                              // Extract direct video source
+var ogVideo *regexp.Regexp = regexp.MustCompile(`<meta property="og:video(:secure_url)?" content="(.*?)"`)          // Extract direct video source from meta tags
+var rWidth *regexp.Regexp = regexp.MustCompile(`<meta property="og:video:width" content="(\d+)"`)                   // Extract video width
+var rHeight *regexp.Regexp = regexp.MustCompile(`<meta property="og:video:height" content="(\d+)"`)                 // Extract video height
+var rDuration *regexp.Regexp = regexp.MustCompile(`<meta property="(video|og:video):duration" content="(\d+)"`)     // Extract duration (seconds)
+var rUploaded *regexp.Regexp = regexp.MustCompile(`itemprop="uploadDate" content="(.*?)"`)                          // Extract upload date
+var rRemoved *regexp.Regexp = regexp.MustCompile(`(?i)removed (due to|because of|following) (a )?(dmca|copyright)`) // Identify DMCA removal notices
 
 // Extract returns a slice of all Sendvid links contained within a string, if any.
 func Extract(res string) ([]string, error) {
@@ -69,22 +77,95 @@ func ExtractViewCount(sendvidContents string) int {
 	return viewcount
 }
 
-// Validate performs a GET request to the Sendvid URL and uses the response status code to identify its validity
-func Validate(x string) (bool, error) {
+// ExtractSource takes the body response/contents of a Sendvid page (raw source/html (formatted as string)) as
+// an argument and returns the direct video source URL found in the player markup as a string.
+func ExtractSource(sendvidContents string) string {
+	if m := rSource.FindStringSubmatch(sendvidContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	if m := ogVideo.FindStringSubmatch(sendvidContents); m != nil {
+		return html.UnescapeString(m[2])
+	}
+	return ""
+}
+
+// ExtractResolution takes the body response/contents of a Sendvid page (raw source/html (formatted as string)) as
+// an argument and returns the video's resolution (e.g. 1280x720) as a string.
+func ExtractResolution(sendvidContents string) string {
+	w := rWidth.FindStringSubmatch(sendvidContents)
+	h := rHeight.FindStringSubmatch(sendvidContents)
+	if w == nil || h == nil {
+		return ""
+	}
+	return w[1] + "x" + h[1]
+}
+
+// ExtractDuration takes the body response/contents of a Sendvid page (raw source/html (formatted as string)) as
+// an argument and returns the video's duration (e.g. 01:02:03) as a string.
+func ExtractDuration(sendvidContents string) string {
+	m := rDuration.FindStringSubmatch(sendvidContents)
+	if m == nil {
+		return ""
+	}
+	seconds, err := strconv.Atoi(m[2])
+	if err != nil {
+		return ""
+	}
+	return handlers.FormatDuration(seconds)
+}
+
+// ExtractUploadDate takes the body response/contents of a Sendvid page (raw source/html (formatted as string))
+// and a dateFormat string as it's arguments. The dateFormat string will be used to format a time.Time into a more
+// favorable format. The default dateFormat used by tempest is "Jan 02, 2006". It will return a date in string format,
+// which has been formatted in accordance with the specified dateFormat, and an error which will be nil if successful.
+func ExtractUploadDate(sendvidContents string, dateFormat string) (string, error) {
+	m := rUploaded.FindStringSubmatch(sendvidContents)
+	if m == nil {
+		return "", nil
+	}
+	parsed, err := time.Parse(time.RFC3339, m[1]) // Convert string to *time.Time
+	if err != nil {
+		return "", err
+	}
+	return parsed.Format(dateFormat), nil // Format the date based on the format specified in dateFormat and return as string
+}
+
+// Availability performs a GET request to the Sendvid URL and identifies whether the video is online, was removed
+// (e.g. following a DMCA notice) or never existed. It returns one of models.Online, models.Removed or
+// models.NotFound, the contents of the page and an error which will be nil if successful.
+func Availability(x string) (string, string, error) {
 	// Perform a GET request using the Sendvid URL
 	res, err := handlers.GetRes(x)
 	if err != nil {
-		return false, err
+		return "", "", err
 	}
 
-	if res.StatusCode == 200 {
-		return true, res.Body.Close()
-	} else {
-		return false, res.Body.Close()
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", "", err
+	}
+	contents := string(body)
+
+	// The removal notice is only looked for on error pages, as the title/description of a live video may contain it
+	switch {
+	case res.StatusCode != 200 && rRemoved.MatchString(contents):
+		return models.Removed, contents, res.Body.Close()
+	case res.StatusCode == 200:
+		return models.Online, contents, res.Body.Close()
+	default:
+		return models.NotFound, contents, res.Body.Close()
 	}
 }
 
-// Delegate takes a string as an argument and returns a slice of valid Senvid links found within the response (if any) and an error
+// Validate performs a GET request to the Sendvid URL and uses the response to identify its validity
+func Validate(x string) (bool, error) {
+	state, _, err := Availability(x)
+	return state == models.Online, err
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Sendvid links found within the response (if any) and an error.
+// Links to removed content are returned too, without metadata and with Status set to models.Removed.
 func Delegate(res, source string) ([]models.Entry, error) {
 	// Use Extract() to extract any existing Sendvid links from the response
 	x, err := Extract(res)
@@ -98,40 +179,29 @@ func Delegate(res, source string) ([]models.Entry, error) {
 		var results []models.Entry = nil
 		// Loop through each Sendvid link within the slice
 		for _, v := range x {
-			// Call the Validate function in order to check whether or not the link is valid
-			x, err := Validate(v)
+			// Call the Availability function in order to check whether or not the link is valid
+			state, contents, err := Availability(v)
 			if err != nil {
 				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
-				handlers.LogErr(err, "error occurred on sendvid delegate attempt to call validate")
+				handlers.LogErr(err, "error occurred on sendvid delegate attempt to call availability")
 				continue
 			}
-			// If x, the bool return by Validate(), is true: output the result to the terminal and append the link to the specified results slice.
-			if x {
-				// Get body contents of the sendvid link
-				res, err := handlers.GetRes(v)
-				if err != nil {
-					handlers.LogErr(err, "error occurred on sendvid delegate attempt to call getres")
-					continue
-				}
-
-				// Read results of the *http.Response body
-				body, err := io.ReadAll(res.Body)
-				if err != nil {
-					handlers.LogErr(err, "error occurred on sendvid delegate attempt to call readall")
-					continue
-				}
-
-				// Convert read results to a string
-				contents := string(body)
-
+			// If the video is online, extract its metadata and append the link to the specified results slice.
+			if state == models.Online {
 				aTitle := ExtractTitle(contents)
 				aThumbnail := ExtractThumbnail(contents)
 				aViewCount := ExtractViewCount(contents)
+				aUploadDate, _ := ExtractUploadDate(contents, "Jan 02, 2006")
 
 				// Create type Entry and specify the respective values
-				ent := models.Entry{Source: source, Link: v, Service: "Sendvid", Thumbnail: aThumbnail, Views: aViewCount, Title: aTitle, Type: "File"}
+				ent := models.Entry{Source: source, Link: v, Service: "Sendvid", Status: models.Online, Thumbnail: aThumbnail, Views: aViewCount, Title: aTitle, Type: "File", Uploaded: aUploadDate, Duration: ExtractDuration(contents), Resolution: ExtractResolution(contents), Direct: ExtractSource(contents)}
 				// Append the entry to the results slice to be returned to the main runner
 				results = append(results, ent)
+			} else if state == models.Removed {
+				// Removed content is reported without metadata, so that takedowns can be told apart from invalid links
+				results = append(results, models.Entry{Source: source, Link: v, Service: "Sendvid", Type: "File", Status: models.Removed})
+			} else {
+				handlers.LogInfo("sendvid link " + v + " is offline: " + state)
 			}
 		}
 		// When the loop is finished, return the results slice
@@ -0,0 +1,61 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package sendvid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+func TestAvailability(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/online":
+			w.Write([]byte(`<html><title>video</title></html>`))
+		case "/online-notice":
+			w.Write([]byte(`<html><title>Clip removed due to copyright, reuploaded</title></html>`))
+		case "/removed":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<p>This video has been removed due to a DMCA notice.</p>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/online", models.Online},
+		{"/online-notice", models.Online},
+		{"/removed", models.Removed},
+		{"/missing", models.NotFound},
+	}
+	for _, tt := range tests {
+		got, _, err := Availability(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Availability(%q) error = %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("Availability(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
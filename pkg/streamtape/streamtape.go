@@ -0,0 +1,160 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package streamtape contains functions that can be used to accurately extract and validate Streamtape links.
+package streamtape
+
+import (
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var sLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(streamtape\.(com|to|net|xyz|site|cc)|strtape\.(cloud|tech)|streamta\.pe|strcloud\.link|tapecontent\.net)/(v|e)/([a-zA-Z0-9]{15})`) // Extract Streamtape links
+var ogTitle *regexp.Regexp = regexp.MustCompile(`<meta name="og:title" content="(.*?)"`)                                                                                                          // Extract title
+var headTitle *regexp.Regexp = regexp.MustCompile(`<h2>(.*?)</h2>`)                                                                                                                               // Extract title from the video page
+var roughSize *regexp.Regexp = regexp.MustCompile(`<p class="subheading">\s*(\d+(?:\.\d+)?\s*[KMGTP]?B)\s*</p>`)                                                                                  // Extract size
+var ogImage *regexp.Regexp = regexp.MustCompile(`<meta name="og:image" content="(.*?)"`)                                                                                                          // Extract thumbnail
+var rRemoved *regexp.Regexp = regexp.MustCompile(`(?i)(removed|deleted) (due to|because of|following) (a )?(dmca|copyright)`)                                                                     // Identify DMCA removal notices
+
+// Extract returns a slice of all Streamtape links contained within a string, if any. Embed links and links on
+// mirror domains are converted to https://streamtape.com/v/<id>, so each video is only processed once.
+func Extract(res string) ([]string, error) {
+	var links []string
+	seen := make(map[string]bool)
+	for _, m := range sLink.FindAllStringSubmatch(res, -1) {
+		link := "https://streamtape.com/v/" + m[6]
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	// Return all Streamtape links found within an http response
+	return links, nil
+}
+
+// ExtractTitle takes the body response/contents of a Streamtape page (raw source/html (formatted as string)) as
+// an argument and returns the video's title as a string.
+func ExtractTitle(streamtapeContents string) string {
+	if m := ogTitle.FindStringSubmatch(streamtapeContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	if m := headTitle.FindStringSubmatch(streamtapeContents); m != nil {
+		return html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	return ""
+}
+
+// ExtractSize takes the body response/contents of a Streamtape page (raw source/html (formatted as string)) as
+// an argument and returns the video's file size as a string.
+func ExtractSize(streamtapeContents string) string {
+	if m := roughSize.FindStringSubmatch(streamtapeContents); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ExtractThumbnail takes the body response/contents of a Streamtape page (raw source/html (formatted as string)) as
+// an argument. The extracted URL is unescaped to ensure validity and returned in string format.
+func ExtractThumbnail(streamtapeContents string) string {
+	if m := ogImage.FindStringSubmatch(streamtapeContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// Availability performs a GET request to the Streamtape URL and identifies whether the video is online, was removed
+// (e.g. following a DMCA notice) or never existed. It returns one of models.Online, models.Removed or
+// models.NotFound, the contents of the page and an error which will be nil if successful.
+func Availability(x string) (string, string, error) {
+	// Perform a GET request using the Streamtape URL
+	res, err := handlers.GetRes(x)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", "", err
+	}
+	contents := string(body)
+
+	// The removal notice is only looked for on error pages, as the title/description of a live file may contain it
+	switch {
+	case res.StatusCode == 451 || (res.StatusCode != 200 && rRemoved.MatchString(contents)):
+		return models.Removed, contents, res.Body.Close()
+	case res.StatusCode == 200 && !strings.Contains(contents, "Video not found"):
+		return models.Online, contents, res.Body.Close()
+	default:
+		return models.NotFound, contents, res.Body.Close()
+	}
+}
+
+// Validate performs a GET request to the Streamtape URL and uses the response to identify its validity
+func Validate(x string) (bool, error) {
+	state, _, err := Availability(x)
+	return state == models.Online, err
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Streamtape links found within the response (if any) and an error.
+// Links to removed content are returned too, without metadata and with Status set to models.Removed.
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Streamtape links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on streamtape delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Streamtape links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Streamtape links
+		var results []models.Entry = nil
+		// Loop through each Streamtape link within the slice
+		for _, v := range x {
+			// Call the Availability function in order to check whether or not the link is valid
+			state, contents, err := Availability(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on streamtape delegate attempt to call availability")
+				continue
+			}
+			// If the video is online, extract its metadata and append the link to the specified results slice.
+			if state == models.Online {
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Streamtape", Status: models.Online, Title: ExtractTitle(contents), Size: ExtractSize(contents), Thumbnail: ExtractThumbnail(contents), Type: "File"}
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			} else if state == models.Removed {
+				// Removed content is reported without metadata, so that takedowns can be told apart from invalid links
+				results = append(results, models.Entry{Source: source, Link: v, Service: "Streamtape", Type: "File", Status: models.Removed})
+			} else {
+				handlers.LogInfo("streamtape link " + v + " is offline: " + state)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
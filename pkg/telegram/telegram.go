@@ -0,0 +1,177 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package telegram contains functions that can be used to accurately extract and validate Telegram channel/group links.
+package telegram
+
+import (
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var tLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(t\.me|telegram\.me|telegram\.dog)/(\+[a-zA-Z0-9_-]{10,}|joinchat/[a-zA-Z0-9_-]{10,}|[a-zA-Z][a-zA-Z0-9_]{4,31})`) // Extract Telegram links
+var roughTitle *regexp.Regexp = regexp.MustCompile(`<div class="tgme_page_title"[^>]*><span dir="auto">(.*?)</span>`)                                                            // Extract channel/group name
+var roughExtra *regexp.Regexp = regexp.MustCompile(`<div class="tgme_page_extra">(.*?)</div>`)                                                                                   // Extract member/subscriber count
+var roughDesc *regexp.Regexp = regexp.MustCompile(`<div class="tgme_page_description"[^>]*>(.*?)</div>`)                                                                         // Extract description
+var photo *regexp.Regexp = regexp.MustCompile(`<img class="tgme_page_photo_image" src="(.*?)"`)                                                                                  // Extract channel/group photo
+var digits *regexp.Regexp = regexp.MustCompile(`^[\d\s,]+`)                                                                                                                      // Extract the leading count
+var tags *regexp.Regexp = regexp.MustCompile(`<[^>]+>`)                                                                                                                          // Strip html tags
+
+// reserved contains t.me paths that are not channels, groups or users
+var reserved = map[string]bool{"share": true, "addstickers": true, "addemoji": true, "addtheme": true, "proxy": true, "socks": true, "setlanguage": true, "login": true, "iv": true}
+
+// Extract returns a slice of all Telegram channel/group/invite links contained within a string, if any. Links are
+// converted to the t.me domain.
+func Extract(res string) ([]string, error) {
+	var links []string
+	for _, m := range tLink.FindAllStringSubmatch(res, -1) {
+		if reserved[strings.ToLower(m[3])] {
+			continue
+		}
+		links = append(links, "https://t.me/"+m[3])
+	}
+	// Return all Telegram links found within an http response
+	return links, nil
+}
+
+// ExtractTitle takes the body response/contents of a Telegram preview page (raw source/html (formatted as string)) as
+// an argument and returns the channel/group name as a string.
+func ExtractTitle(telegramContents string) string {
+	if m := roughTitle.FindStringSubmatch(telegramContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// ExtractDescription takes the body response/contents of a Telegram preview page (raw source/html (formatted as string))
+// as an argument and returns the channel/group description as a string.
+func ExtractDescription(telegramContents string) string {
+	if m := roughDesc.FindStringSubmatch(telegramContents); m != nil {
+		return html.UnescapeString(tags.ReplaceAllString(strings.ReplaceAll(m[1], "<br/>", "\n"), ""))
+	}
+	return ""
+}
+
+// ExtractThumbnail takes the body response/contents of a Telegram preview page (raw source/html (formatted as string))
+// as an argument and returns the channel/group photo URL as a string.
+func ExtractThumbnail(telegramContents string) string {
+	if m := photo.FindStringSubmatch(telegramContents); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
+
+// ExtractType takes the body response/contents of a Telegram preview page (raw source/html (formatted as string)) as an
+// argument and returns the type of the link: Channel (subscribers), Group (members) or User.
+func ExtractType(telegramContents string) string {
+	extra := ""
+	if m := roughExtra.FindStringSubmatch(telegramContents); m != nil {
+		extra = m[1]
+	}
+	switch {
+	case strings.Contains(extra, "subscriber"):
+		return "Channel"
+	case strings.Contains(extra, "member"):
+		return "Group"
+	default:
+		return "User"
+	}
+}
+
+// ExtractMemberCount takes the body response/contents of a Telegram preview page (raw source/html (formatted as string))
+// as an argument and returns the channel's subscriber count/group's member count as an integer. It will return -1 if
+// the count is unavailable.
+func ExtractMemberCount(telegramContents string) int {
+	m := roughExtra.FindStringSubmatch(telegramContents)
+	if m == nil {
+		return -1
+	}
+	count := digits.FindString(m[1])
+	count = strings.NewReplacer(" ", "", ",", "").Replace(count)
+	c, err := strconv.Atoi(count)
+	if err != nil {
+		return -1
+	}
+	return c
+}
+
+// Validate performs a GET request to the Telegram URL and uses the preview page contents to identify its validity.
+// Preview pages of invalid/expired links do not contain a title. It returns the contents of the page alongside its
+// validity.
+func Validate(x string) (bool, string, error) {
+	// Perform a GET request using the Telegram URL
+	res, err := handlers.GetRes(x)
+	if err != nil {
+		return false, "", err
+	}
+
+	// Prepare the contents of the response to be read
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	if res.StatusCode == 200 && strings.Contains(string(body), `class="tgme_page_title"`) {
+		return true, string(body), res.Body.Close()
+	} else {
+		return false, string(body), res.Body.Close()
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Telegram links found within the response (if any) and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Telegram links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on telegram delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Telegram links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Telegram links
+		var results []models.Entry = nil
+		// Loop through each Telegram link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on telegram delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Telegram", Title: ExtractTitle(contents), Description: ExtractDescription(contents), Thumbnail: ExtractThumbnail(contents), Type: ExtractType(contents), Members: ExtractMemberCount(contents)}
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package telegram
+
+import (
+	"reflect"
+	"testing"
+)
+
+// channel is a trimmed t.me preview page of a public channel
+const channel = `<div class="tgme_page_photo"><a href="tg://resolve?domain=tempest"><img class="tgme_page_photo_image" src="https://cdn4.telesco.pe/file/abc.jpg?a=1&amp;b=2"></a></div>
+<div class="tgme_page_title" dir="auto"><span dir="auto">Tempest &amp; Co</span></div>
+<div class="tgme_page_extra">12 345 subscribers</div>
+<div class="tgme_page_description" dir="auto">First line<br/>Second <a href="https://example.com">link</a></div>`
+
+func TestExtract(t *testing.T) {
+	in := `https://t.me/tempest_news https://telegram.me/+AbCdEfGhIj12 https://t.me/joinchat/AbCdEfGhIj12
+		https://t.me/share https://t.me/abc https://telegram.dog/Another_One`
+	want := []string{"https://t.me/tempest_news", "https://t.me/+AbCdEfGhIj12", "https://t.me/joinchat/AbCdEfGhIj12", "https://t.me/Another_One"}
+	got, err := Extract(in)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	if got := ExtractTitle(channel); got != "Tempest & Co" {
+		t.Errorf("ExtractTitle() = %q", got)
+	}
+	if got := ExtractDescription(channel); got != "First line\nSecond link" {
+		t.Errorf("ExtractDescription() = %q", got)
+	}
+	if got := ExtractThumbnail(channel); got != "https://cdn4.telesco.pe/file/abc.jpg?a=1&b=2" {
+		t.Errorf("ExtractThumbnail() = %q", got)
+	}
+}
+
+func TestExtractTypeAndMembers(t *testing.T) {
+	tests := []struct {
+		page, kind string
+		members    int
+	}{
+		{channel, "Channel", 12345},
+		{`<div class="tgme_page_extra">1,024 members, 17 online</div>`, "Group", 1024},
+		{`<div class="tgme_page_extra">@someone</div>`, "User", -1},
+		{`<html></html>`, "User", -1},
+	}
+	for _, tt := range tests {
+		if got := ExtractType(tt.page); got != tt.kind {
+			t.Errorf("ExtractType(%q) = %q, want %q", tt.page, got, tt.kind)
+		}
+		if got := ExtractMemberCount(tt.page); got != tt.members {
+			t.Errorf("ExtractMemberCount(%q) = %d, want %d", tt.page, got, tt.members)
+		}
+	}
+}
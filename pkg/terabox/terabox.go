@@ -0,0 +1,160 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package terabox contains functions that can be used to accurately extract and validate Terabox links.
+package terabox
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var tLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(www\.)?(terabox\.com|terabox\.app|teraboxapp\.com|1024terabox\.com|4funbox\.com|mirrobox\.com|nephobox\.com)/s/(1[a-zA-Z0-9_-]{10,})`)
+
+// Extract returns a slice of all Terabox links contained within a string, if any. Links on mirror domains are
+// converted to terabox.com.
+func Extract(res string) ([]string, error) {
+	var links []string
+	for _, m := range tLink.FindAllStringSubmatch(res, -1) {
+		links = append(links, "https://www.terabox.com/s/"+m[4])
+	}
+	// Return all Terabox links found within an http response
+	return links, nil
+}
+
+// ExtractShortURL returns the short URL (share ID, including its leading 1) of a Terabox link
+func ExtractShortURL(link string) string {
+	m := tLink.FindStringSubmatch(link)
+	if m == nil {
+		return ""
+	}
+	return m[4]
+}
+
+// ExtractInfo takes the contents of the body response from the Terabox shorturlinfo endpoint and unmarshals
+// it, returning the metadata as type *models.TBShare alongside an error
+func ExtractInfo(teraboxContents string) (*models.TBShare, error) {
+	info := new(models.TBShare)
+	err := json.Unmarshal([]byte(teraboxContents), &info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Validate takes a Terabox link/URL and resolves its short URL through the public shorturlinfo endpoint to identify
+// whether or not the link is valid/online. It returns the contents of the API response alongside its validity.
+func Validate(x string) (bool, string, error) {
+	// Perform a GET request using the Terabox API
+	res, err := handlers.GetRes("https://www.terabox.com/api/shorturlinfo?root=1&shorturl=" + ExtractShortURL(x))
+	if err != nil {
+		return false, "", err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	err = res.Body.Close()
+
+	// The API responds with a non-zero errno for invalid/expired shares
+	info, uErr := ExtractInfo(string(body))
+	if res.StatusCode == 200 && uErr == nil && info.Errno == 0 {
+		return true, string(body), err
+	} else {
+		return false, string(body), err
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Terabox links found within the response (if any) or nil, and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Terabox links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on terabox delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Terabox links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Terabox links
+		var results []models.Entry = nil
+		// Loop through each Terabox link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on terabox delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				tb, err := ExtractInfo(contents)
+				if err != nil {
+					handlers.LogErr(err, "error occurred on terabox delegate attempt to extract metadata")
+					continue
+				}
+
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Terabox", Title: tb.Title, FileCount: len(tb.List)}
+				if tb.Ctime > 0 {
+					ent.Uploaded = time.Unix(tb.Ctime, 0).UTC().Format("Jan 02, 2006")
+				}
+
+				var total int64
+				for _, item := range tb.List {
+					total += item.Size
+					child := models.Child{Name: item.ServerFilename, Link: v, Size: handlers.FormatBytes(item.Size), Mtime: time.Unix(item.ServerCtime, 0).UTC().Format(time.RFC3339), Hash: item.MD5}
+					if item.IsDir == 1 {
+						child.Type = "Folder"
+						child.Size = ""
+					} else {
+						child.Type = "File"
+					}
+					ent.Children = append(ent.Children, child)
+				}
+				ent.Size = handlers.FormatBytes(total)
+
+				// A share containing a single file is reported as that file, otherwise as a folder
+				if len(tb.List) == 1 && tb.List[0].IsDir == 0 {
+					ent.Type = "File"
+					ent.Children = nil
+				} else {
+					ent.Type = "Folder"
+				}
+				if ent.Title == "" && len(tb.List) > 0 {
+					ent.Title = tb.List[0].ServerFilename
+				}
+
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,125 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package torrent
+
+import (
+	"errors"
+	"strconv"
+)
+
+// errBencode is returned when a .torrent file is not valid bencode
+var errBencode = errors.New("invalid bencode data")
+
+// decoder is a minimal bencode decoder. Dictionaries are decoded to map[string]interface{}, lists to []interface{},
+// integers to int64 and byte strings to string. The raw bytes of the top level "info" dictionary are retained so
+// that the infohash can be calculated.
+type decoder struct {
+	data []byte
+	pos  int
+	info []byte
+}
+
+// decode decodes the value starting at the decoder's current position
+func (d *decoder) decode(depth int) (interface{}, error) {
+	if d.pos >= len(d.data) || depth > 64 {
+		return nil, errBencode
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		end := d.index('e')
+		if end < 0 {
+			return nil, errBencode
+		}
+		n, err := strconv.ParseInt(string(d.data[d.pos+1:end]), 10, 64)
+		if err != nil {
+			return nil, errBencode
+		}
+		d.pos = end + 1
+		return n, nil
+	case c == 'l':
+		d.pos++
+		var list []interface{}
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		// The list must be terminated before the end of the data
+		if d.pos >= len(d.data) {
+			return nil, errBencode
+		}
+		d.pos++
+		return list, nil
+	case c == 'd':
+		d.pos++
+		dict := make(map[string]interface{})
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, errBencode
+			}
+			start := d.pos
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if key == "info" && depth == 0 {
+				d.info = d.data[start:d.pos]
+			}
+			dict[key] = v
+		}
+		// The dictionary must be terminated before the end of the data
+		if d.pos >= len(d.data) {
+			return nil, errBencode
+		}
+		d.pos++
+		return dict, nil
+	case c >= '0' && c <= '9':
+		colon := d.index(':')
+		if colon < 0 {
+			return nil, errBencode
+		}
+		n, err := strconv.Atoi(string(d.data[d.pos:colon]))
+		// Compare against the remaining length, colon+1+n overflows for huge lengths
+		if err != nil || n < 0 || n > len(d.data)-colon-1 {
+			return nil, errBencode
+		}
+		s := string(d.data[colon+1 : colon+1+n])
+		d.pos = colon + 1 + n
+		return s, nil
+	default:
+		return nil, errBencode
+	}
+}
+
+// index returns the position of the next occurrence of c, or -1 if there is none
+func (d *decoder) index(c byte) int {
+	for i := d.pos; i < len(d.data); i++ {
+		if d.data[i] == c {
+			return i
+		}
+	}
+	return -1
+}
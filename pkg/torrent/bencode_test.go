@@ -0,0 +1,107 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"overflowing string length", "9223372036854775807:"},
+		{"string longer than data", "10:abc"},
+		{"negative string length", "-1:a"},
+		{"unterminated integer", "i42"},
+		{"invalid integer", "iabce"},
+		{"unterminated list", "l4:spam"},
+		{"unterminated dict", "d3:foo3:bar"},
+		{"unterminated info dict", "d4:infod4:name4:spam"},
+		{"non string key", "di1e3:fooe"},
+		{"unknown type", "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &decoder{data: []byte(tt.data)}
+			if _, err := d.decode(0); err != errBencode {
+				t.Errorf("decode(%q) error = %v, want %v", tt.data, err, errBencode)
+			}
+		})
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	data := make([]byte, 0, 200)
+	for i := 0; i < 100; i++ {
+		data = append(data, 'l')
+	}
+	d := &decoder{data: data}
+	if _, err := d.decode(0); err != errBencode {
+		t.Errorf("decode(nested lists) error = %v, want %v", err, errBencode)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	d := &decoder{data: []byte("d3:bar4:spam3:fooi42e4:listl1:a1:bee")}
+	v, err := d.decode(0)
+	if err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	dict, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("decode() = %T, want map", v)
+	}
+	if dict["bar"] != "spam" || dict["foo"] != int64(42) {
+		t.Errorf("decode() = %v", dict)
+	}
+	if list, _ := dict["list"].([]interface{}); len(list) != 2 || list[0] != "a" || list[1] != "b" {
+		t.Errorf("decode() list = %v", dict["list"])
+	}
+}
+
+func TestParseTorrent(t *testing.T) {
+	info := "d5:filesld6:lengthi1024e4:pathl3:dir5:a.txteed6:lengthi2048e4:pathl5:b.txteee4:name4:test12:piece lengthi16384ee"
+	data := "d8:announce10:http://a/a13:announce-listll10:http://a/ael10:http://b/aee4:info" + info + "e"
+	ent, err := ParseTorrent([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseTorrent() error = %v", err)
+	}
+	sum := sha1.Sum([]byte(info))
+	if ent.Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Hash = %s, want %s", ent.Hash, hex.EncodeToString(sum[:]))
+	}
+	if ent.Title != "test" || ent.FileCount != 2 || len(ent.Trackers) != 2 {
+		t.Errorf("ParseTorrent() = %+v", ent)
+	}
+	if ent.Children[0].Name != "dir/a.txt" {
+		t.Errorf("Children[0].Name = %q, want dir/a.txt", ent.Children[0].Name)
+	}
+}
+
+func TestParseTorrentInvalid(t *testing.T) {
+	for _, data := range []string{"9223372036854775807:", "d4:infod4:name4:test", "l4:infoe", "d3:foo3:bare"} {
+		if _, err := ParseTorrent([]byte(data)); err == nil {
+			t.Errorf("ParseTorrent(%q) error = nil, want error", data)
+		}
+	}
+}
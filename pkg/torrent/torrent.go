@@ -0,0 +1,285 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package torrent contains functions that can be used to extract and parse BitTorrent magnet links and .torrent files.
+// Magnet links are parsed entirely offline.
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var mLink *regexp.Regexp = regexp.MustCompile(`magnet:\?[^\s"'<>)\]]*xt=urn:btih:[a-zA-Z0-9]{32,40}[^\s"'<>)\]]*`) // Extract magnet links
+var tLink *regexp.Regexp = regexp.MustCompile(`(https|http)://[^\s"'<>)\]]+\.torrent(\?[^\s"'<>)\]]*)?`)           // Extract .torrent file links
+
+// MaxTorrentSize is the maximum number of bytes read from a linked .torrent file
+var MaxTorrentSize int64 = 10 << 20
+
+// Magnet represents the information contained within a magnet link
+type Magnet struct {
+	InfoHash string   // Hex encoded infohash
+	Base32   string   // Base32 encoded infohash
+	Name     string   // Display name (dn)
+	Length   int64    // Exact length (xl), -1 if absent
+	Trackers []string // Trackers (tr)
+}
+
+// Extract returns a slice of all magnet links contained within a string, if any.
+func Extract(res string) ([]string, error) {
+	var links []string
+	for _, v := range mLink.FindAllString(res, -1) {
+		links = append(links, html.UnescapeString(v))
+	}
+	// Return all magnet links found within an http response
+	return links, nil
+}
+
+// ExtractTorrents returns a slice of all .torrent file links contained within a string, if any.
+func ExtractTorrents(res string) ([]string, error) {
+	return tLink.FindAllString(res, -1), nil
+}
+
+// ExtractLinks returns a slice of all magnet links and .torrent file links contained within a string, if any. These
+// are the links validated by Delegate.
+func ExtractLinks(res string) ([]string, error) {
+	links, err := Extract(res)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ExtractTorrents(res)
+	if err != nil {
+		return nil, err
+	}
+	return append(links, t...), nil
+}
+
+// ParseMagnet parses a magnet link without performing any requests, returning its infohash (in hex and base32),
+// display name, exact length and trackers as type *Magnet alongside an error.
+func ParseMagnet(link string) (*Magnet, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	m := &Magnet{Name: q.Get("dn"), Length: -1, Trackers: q["tr"]}
+	for _, xt := range q["xt"] {
+		if strings.HasPrefix(xt, "urn:btih:") {
+			m.InfoHash, m.Base32, err = ConvertHash(strings.TrimPrefix(xt, "urn:btih:"))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.InfoHash == "" {
+		return nil, errors.New("magnet link does not contain a btih infohash")
+	}
+	if xl, err := strconv.ParseInt(q.Get("xl"), 10, 64); err == nil {
+		m.Length = xl
+	}
+	return m, nil
+}
+
+// ConvertHash takes a BitTorrent v1 infohash in either hex (40 characters) or base32 (32 characters) encoding and
+// returns it in both encodings (lowercase hex, uppercase base32), alongside an error.
+func ConvertHash(hash string) (string, string, error) {
+	var raw []byte
+	var err error
+	switch len(hash) {
+	case 40:
+		raw, err = hex.DecodeString(hash)
+	case 32:
+		raw, err = base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+	default:
+		err = errors.New("infohash has an invalid length")
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(raw), base32.StdEncoding.EncodeToString(raw), nil
+}
+
+// ParseTorrent parses the contents of a .torrent file, returning an entry containing its name, total size, infohash,
+// trackers and the files contained within it, alongside an error.
+func ParseTorrent(contents []byte) (*models.Entry, error) {
+	d := &decoder{data: contents}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := v.(map[string]interface{})
+	if !ok || d.info == nil {
+		return nil, errBencode
+	}
+	info, _ := meta["info"].(map[string]interface{})
+
+	sum := sha1.Sum(d.info)
+	ent := &models.Entry{Type: "Torrent", MimeType: "application/x-bittorrent", Hash: hex.EncodeToString(sum[:]), AltHash: base32.StdEncoding.EncodeToString(sum[:])}
+	ent.Title, _ = info["name"].(string)
+
+	// Collect trackers from announce and announce-list, without duplicates
+	seen := make(map[string]bool)
+	addTracker := func(t interface{}) {
+		if s, ok := t.(string); ok && s != "" && !seen[s] {
+			seen[s] = true
+			ent.Trackers = append(ent.Trackers, s)
+		}
+	}
+	addTracker(meta["announce"])
+	if tiers, ok := meta["announce-list"].([]interface{}); ok {
+		for _, tier := range tiers {
+			if list, ok := tier.([]interface{}); ok {
+				for _, t := range list {
+					addTracker(t)
+				}
+			}
+		}
+	}
+
+	var total int64
+	if files, ok := info["files"].([]interface{}); ok {
+		// Multi-file torrent
+		for _, f := range files {
+			file, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			length, _ := file["length"].(int64)
+			var parts []string
+			if p, ok := file["path"].([]interface{}); ok {
+				for _, part := range p {
+					if s, ok := part.(string); ok {
+						parts = append(parts, s)
+					}
+				}
+			}
+			total += length
+			ent.Children = append(ent.Children, models.Child{Name: path.Join(parts...), Type: "File", Size: handlers.FormatBytes(length)})
+		}
+		ent.FileCount = len(ent.Children)
+	} else {
+		// Single-file torrent
+		total, _ = info["length"].(int64)
+		ent.FileCount = 1
+	}
+	ent.Size = handlers.FormatBytes(total)
+	return ent, nil
+}
+
+// Validate checks whether a magnet link contains a valid infohash. No requests are performed.
+func Validate(x string) (bool, error) {
+	_, err := ParseMagnet(x)
+	return err == nil, err
+}
+
+// ValidateTorrent performs a GET request to the .torrent file URL and uses the response status code to identify its
+// validity. It returns the contents of the file (up to MaxTorrentSize bytes) alongside its validity.
+func ValidateTorrent(x string) (bool, []byte, error) {
+	res, err := handlers.GetRes(x)
+	if err != nil {
+		return false, nil, err
+	}
+
+	// Prepare the contents of the response to be read, limited to MaxTorrentSize bytes
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxTorrentSize))
+	if err != nil {
+		return false, nil, err
+	}
+
+	if res.StatusCode == 200 {
+		return true, body, res.Body.Close()
+	} else {
+		return false, body, res.Body.Close()
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid magnet links and .torrent files found within
+// the response (if any) and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing magnet links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on torrent delegate attempt to call extract")
+		return nil, err
+	}
+	// Use ExtractTorrents() to extract any existing .torrent file links from the response
+	t, err := ExtractTorrents(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on torrent delegate attempt to call extracttorrents")
+		return nil, err
+	}
+	// Check if the return slices of magnet/.torrent links are empty
+	if len(x) > 0 || len(t) > 0 {
+		// Create a new, empty slice where we will append any valid links
+		var results []models.Entry = nil
+		// Loop through each magnet link within the slice, these are parsed without performing any requests
+		for _, v := range x {
+			m, err := ParseMagnet(v)
+			if err != nil {
+				handlers.LogErr(err, "error occurred on torrent delegate attempt to parse magnet link")
+				continue
+			}
+
+			// Create type Entry and specify the respective values
+			ent := models.Entry{Source: source, Link: v, Service: "BitTorrent", Title: m.Name, Type: "Magnet", Hash: m.InfoHash, AltHash: m.Base32, Trackers: m.Trackers}
+			if m.Length >= 0 {
+				ent.Size = handlers.FormatBytes(m.Length)
+			}
+			// Append the entry to the results slice to be returned to the main runner
+			results = append(results, ent)
+		}
+		// Loop through each .torrent file link within the slice
+		for _, v := range t {
+			// Call the ValidateTorrent function in order to check whether or not the link is valid
+			x, contents, err := ValidateTorrent(v)
+			if err != nil {
+				handlers.LogErr(err, "error occurred on torrent delegate attempt to call validatetorrent")
+				continue
+			}
+			if x {
+				ent, err := ParseTorrent(contents)
+				if err != nil {
+					handlers.LogErr(err, "error occurred on torrent delegate attempt to parse .torrent file")
+					continue
+				}
+				ent.Source = source
+				ent.Link = v
+				ent.Service = "BitTorrent"
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, *ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,171 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package yandexdisk contains functions that can be used to accurately extract and validate Yandex Disk links.
+package yandexdisk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ax-i-om/tempest/internal/handlers"
+	"github.com/ax-i-om/tempest/pkg/models"
+)
+
+// Compile RegEx expressions for extraction of links/metadata
+var yLink *regexp.Regexp = regexp.MustCompile(`(https|http)://(disk\.yandex\.(ru|com|com\.tr|kz|by|ua)|yadi\.sk)/(d|i)/([a-zA-Z0-9_-]{10,})`)
+
+// MaxItems is the maximum number of items requested when listing the contents of a public folder
+var MaxItems = 500
+
+// Extract returns a slice of all Yandex Disk links contained within a string, if any.
+func Extract(res string) ([]string, error) {
+	// Return all Yandex Disk links found within an http response
+	return yLink.FindAllString(res, -1), nil
+}
+
+// ExtractInfo takes the contents of the body response from the Yandex Disk public resources endpoint and unmarshals
+// it, returning the metadata as type *models.YDResource alongside an error
+func ExtractInfo(yandexContents string) (*models.YDResource, error) {
+	info := new(models.YDResource)
+	err := json.Unmarshal([]byte(yandexContents), &info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ChildLink returns the link of an item within a public folder: the public link of the folder followed by the path of
+// the item (as returned by the API, relative to the folder), with every segment of the path escaped
+func ChildLink(link, path string) string {
+	link = strings.TrimSuffix(link, "/")
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			link += "/" + url.PathEscape(segment)
+		}
+	}
+	return link
+}
+
+// FormatDate converts a date returned by the Yandex Disk API into the specified dateFormat. The default dateFormat
+// used by tempest is "Jan 02, 2006". An empty string is returned if the date could not be parsed.
+func FormatDate(date, dateFormat string) string {
+	parsed, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return ""
+	}
+	return parsed.Format(dateFormat)
+}
+
+// Validate takes a Yandex Disk link/URL and resolves its public key through the public resources endpoint to
+// identify whether or not the link is valid/online. It returns the contents of the API response alongside its validity.
+func Validate(x string) (bool, string, error) {
+	// Perform a GET request using the Yandex Disk API, the public link itself serves as the public key
+	res, err := handlers.GetRes("https://cloud-api.yandex.net/v1/disk/public/resources?public_key=" + url.QueryEscape(x) + "&limit=" + fmt.Sprint(MaxItems))
+	if err != nil {
+		return false, "", err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, "", err
+	}
+
+	err = res.Body.Close()
+
+	// The API responds with a 404 and a DiskNotFoundError for invalid/removed links
+	if res.StatusCode == 200 {
+		return true, string(body), err
+	} else {
+		return false, string(body), err
+	}
+}
+
+// Delegate takes a string as an argument and returns a slice of valid Yandex Disk links found within the response (if any) or nil, and an error
+func Delegate(res, source string) ([]models.Entry, error) {
+	// Use Extract() to extract any existing Yandex Disk links from the response
+	x, err := Extract(res)
+	if err != nil {
+		handlers.LogErr(err, "error occurred on yandexdisk delegate attempt to call extract")
+		return nil, err
+	}
+	// Check if the return slice of Yandex Disk links is empty
+	if len(x) > 0 {
+		// Create a new, empty slice where we will append any valid Yandex Disk links
+		var results []models.Entry = nil
+		// Loop through each Yandex Disk link within the slice
+		for _, v := range x {
+			// Call the Validate function in order to check whether or not the link is valid
+			x, contents, err := Validate(v)
+			if err != nil {
+				// If any error occurs during the validation process, stop the current iteration and immediately begin with the next link within the slice
+				handlers.LogErr(err, "error occurred on yandexdisk delegate attempt to call validate")
+				continue
+			}
+			// If x, the bool return by Validate(), is true: append the link to the specified results slice.
+			if x {
+				yd, err := ExtractInfo(contents)
+				if err != nil {
+					handlers.LogErr(err, "error occurred on yandexdisk delegate attempt to extract metadata")
+					continue
+				}
+
+				// Create type Entry and specify the respective values
+				ent := models.Entry{Source: source, Link: v, Service: "Yandex Disk", Title: yd.Name, MimeType: yd.MimeType, Uploaded: FormatDate(yd.Created, "Jan 02, 2006"), Mtime: yd.Modified, Hash: yd.SHA256}
+
+				if yd.Type == "dir" {
+					var total int64
+					for _, item := range yd.Embedded.Items {
+						total += item.Size
+						// Items that were published themselves have their own public link
+						link := item.PublicURL
+						if link == "" {
+							link = ChildLink(v, item.Path)
+						}
+						child := models.Child{Name: item.Name, Link: link, MimeType: item.MimeType, Size: handlers.FormatBytes(item.Size), Mtime: item.Modified, Hash: item.SHA256}
+						if item.Type == "dir" {
+							child.Type = "Folder"
+							child.Size = ""
+						} else {
+							child.Type = "File"
+						}
+						ent.Children = append(ent.Children, child)
+					}
+					ent.Type = "Folder"
+					ent.FileCount = yd.Embedded.Total
+					ent.Size = handlers.FormatBytes(total)
+				} else {
+					ent.Type = "File"
+					ent.Size = handlers.FormatBytes(yd.Size)
+				}
+
+				// Append the entry to the results slice to be returned to the main runner
+				results = append(results, ent)
+			}
+		}
+		// When the loop is finished, return the results slice
+		return results, nil
+	}
+	// Return nothing, if nothing happens (bruh)
+	return nil, nil
+}
@@ -0,0 +1,73 @@
+/*
+Tempest- Leveraging paste sites as a medium for discovery
+Copyright © 2023 ax-i-om <addressaxiom@pm.me>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package yandexdisk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	contents := `see https://disk.yandex.ru/d/AbCdEf_123-x and http://yadi.sk/i/0123456789ab,
+https://disk.yandex.com.tr/d/abcdefghij. Not: https://disk.yandex.ru/d/short https://disk.yandex.org/d/abcdefghijk`
+	want := []string{"https://disk.yandex.ru/d/AbCdEf_123-x", "http://yadi.sk/i/0123456789ab", "https://disk.yandex.com.tr/d/abcdefghij"}
+	if got, _ := Extract(contents); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractInfo(t *testing.T) {
+	contents := `{"name":"folder","path":"/","type":"dir","created":"2023-04-05T06:07:08+00:00","_embedded":{"total":2,"items":[
+		{"name":"a b.txt","path":"/sub dir/a b.txt","type":"file","mime_type":"text/plain","size":3},
+		{"name":"shared","path":"/shared","type":"dir","public_url":"https://yadi.sk/d/publishedkey1"}]}}`
+	info, err := ExtractInfo(contents)
+	if err != nil {
+		t.Fatalf("ExtractInfo() error = %v", err)
+	}
+	if info.Name != "folder" || info.Type != "dir" || info.Embedded.Total != 2 || len(info.Embedded.Items) != 2 {
+		t.Errorf("ExtractInfo() = %+v", info)
+	}
+	if info.Embedded.Items[1].PublicURL != "https://yadi.sk/d/publishedkey1" {
+		t.Errorf("public_url = %q", info.Embedded.Items[1].PublicURL)
+	}
+	if got := FormatDate(info.Created, "Jan 02, 2006"); got != "Apr 05, 2023" {
+		t.Errorf("FormatDate() = %q", got)
+	}
+	if got := FormatDate("garbage", "Jan 02, 2006"); got != "" {
+		t.Errorf("FormatDate(garbage) = %q, want empty", got)
+	}
+	if _, err := ExtractInfo(`{"name":`); err == nil {
+		t.Error("ExtractInfo() accepted truncated JSON")
+	}
+}
+
+func TestChildLink(t *testing.T) {
+	tests := []struct {
+		link, path, want string
+	}{
+		{"https://disk.yandex.ru/d/abcdefghij", "/a.txt", "https://disk.yandex.ru/d/abcdefghij/a.txt"},
+		{"https://disk.yandex.ru/d/abcdefghij/", "/sub dir/a b.txt", "https://disk.yandex.ru/d/abcdefghij/sub%20dir/a%20b.txt"},
+		{"https://disk.yandex.ru/d/abcdefghij", "/фото/#1?.jpg", "https://disk.yandex.ru/d/abcdefghij/%D1%84%D0%BE%D1%82%D0%BE/%231%3F.jpg"},
+		{"https://disk.yandex.ru/d/abcdefghij", "", "https://disk.yandex.ru/d/abcdefghij"},
+	}
+	for _, tt := range tests {
+		if got := ChildLink(tt.link, tt.path); got != tt.want {
+			t.Errorf("ChildLink(%q, %q) = %q, want %q", tt.link, tt.path, got, tt.want)
+		}
+	}
+}